@@ -0,0 +1,174 @@
+package fauxgl
+
+import (
+	"context"
+	"image"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	tileSize  = 64
+	batchSize = 1 << 14
+)
+
+// rasterTriangle is a clipped, culled, screen space triangle ready to be
+// rasterized.
+type rasterTriangle struct {
+	v0, v1, v2 Vertex
+	s0, s1, s2 Vector
+	// index of the primitive within the draw call, set by drawBinned
+	index int32
+	// line is set if the triangle is part of a styled line
+	line *lineSegment
+}
+
+// tileRange returns the range of tiles overlapped by the triangle's
+// bounding box. ok is false if the triangle is entirely off screen.
+func (dc *Context) tileRange(t *rasterTriangle) (tx0, ty0, tx1, ty1 int, ok bool) {
+	b := dc.bounds
+	min := t.s0.Min(t.s1.Min(t.s2)).Floor()
+	max := t.s0.Max(t.s1.Max(t.s2)).Ceil()
+	if max.X < float64(b.Min.X) || max.Y < float64(b.Min.Y) ||
+		min.X >= float64(b.Max.X) || min.Y >= float64(b.Max.Y) {
+		return
+	}
+	x0 := ClampInt(int(min.X), b.Min.X, b.Max.X-1)
+	y0 := ClampInt(int(min.Y), b.Min.Y, b.Max.Y-1)
+	x1 := ClampInt(int(max.X), b.Min.X, b.Max.X-1)
+	y1 := ClampInt(int(max.Y), b.Min.Y, b.Max.Y-1)
+	return x0 / tileSize, y0 / tileSize, x1 / tileSize, y1 / tileSize, true
+}
+
+// parallel splits the range [0, n) into one contiguous chunk per worker and
+// calls f for each chunk concurrently, returning when all calls are done.
+func parallel(wn, n int, f func(wi, i0, i1 int)) {
+	var wg sync.WaitGroup
+	for wi := 0; wi < wn; wi++ {
+		wg.Add(1)
+		go func(wi int) {
+			defer wg.Done()
+			f(wi, n*wi/wn, n*(wi+1)/wn)
+		}(wi)
+	}
+	wg.Wait()
+}
+
+// canceled reports whether done is closed, without blocking.
+func canceled(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// drawBinned draws count primitives using a tiled rasterizer. setup is called
+// (concurrently) for each primitive index and appends the screen space
+// triangles for that primitive to buf. Triangles are sorted into fixed size
+// screen tiles and each tile is rasterized by a single worker in submission
+// order, so no locking is needed and the output is deterministic. If prepare
+// is not nil it is called before each batch of primitives is set up. If ctx
+// is canceled, drawing stops and ctx.Err() is returned, leaving the buffers
+// partially drawn.
+func (dc *Context) drawBinned(ctx context.Context, count int, prepare func(start, end int), setup func(i int, buf []rasterTriangle) []rasterTriangle) (RasterizeInfo, error) {
+	dc.beginDraw()
+	if dc.bounds.Empty() {
+		return RasterizeInfo{}, nil
+	}
+	done := ctx.Done()
+	wn := dc.workers()
+	nx := (dc.Width + tileSize - 1) / tileSize
+	ny := (dc.Height + tileSize - 1) / tileSize
+	bins := make([][]*rasterTriangle, nx*ny)
+	chunks := make([][]rasterTriangle, wn)
+
+	var result RasterizeInfo
+	for start := 0; start < count; start += batchSize {
+		end := MinInt(start+batchSize, count)
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		if prepare != nil {
+			prepare(start, end)
+		}
+
+		// vertex shading, clipping and culling in contiguous chunks
+		parallel(wn, end-start, func(wi, i0, i1 int) {
+			buf := chunks[wi][:0]
+			for i := start + i0; i < start+i1; i++ {
+				if i%256 == 0 && canceled(done) {
+					break
+				}
+				k := len(buf)
+				buf = setup(i, buf)
+				for ; k < len(buf); k++ {
+					buf[k].index = int32(i)
+				}
+			}
+			chunks[wi] = buf
+		})
+
+		// sort triangles into tiles, preserving submission order
+		for i := range bins {
+			bins[i] = bins[i][:0]
+		}
+		for _, chunk := range chunks {
+			for i := range chunk {
+				t := &chunk[i]
+				tx0, ty0, tx1, ty1, ok := dc.tileRange(t)
+				if !ok {
+					continue
+				}
+				for ty := ty0; ty <= ty1; ty++ {
+					for tx := tx0; tx <= tx1; tx++ {
+						j := ty*nx + tx
+						bins[j] = append(bins[j], t)
+					}
+				}
+			}
+		}
+
+		// rasterize each tile with a single worker
+		var next int64 = -1
+		ch := make(chan RasterizeInfo, wn)
+		for wi := 0; wi < wn; wi++ {
+			go func() {
+				var info RasterizeInfo
+				for {
+					j := int(atomic.AddInt64(&next, 1))
+					if j >= len(bins) || canceled(done) {
+						break
+					}
+					tx := j % nx
+					ty := j / nx
+					bounds := image.Rect(
+						tx*tileSize, ty*tileSize,
+						(tx+1)*tileSize, (ty+1)*tileSize)
+					bounds = bounds.Intersect(dc.bounds)
+					for _, t := range bins[j] {
+						info = info.Add(dc.rasterize(t, bounds))
+					}
+				}
+				ch <- info
+			}()
+		}
+		for wi := 0; wi < wn; wi++ {
+			result = result.Add(<-ch)
+		}
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if dc.Progress != nil {
+			dc.Progress(end, count)
+		}
+	}
+	return result, nil
+}
+
+// workers returns the number of goroutines to use for drawing.
+func (dc *Context) workers() int {
+	return MaxInt(dc.Workers, 1)
+}
@@ -0,0 +1,106 @@
+package fauxgl
+
+import "math"
+
+type BlendFactor int
+
+const (
+	_ BlendFactor = iota
+	BlendZero
+	BlendOne
+	BlendSrcColor
+	BlendOneMinusSrcColor
+	BlendDstColor
+	BlendOneMinusDstColor
+	BlendSrcAlpha
+	BlendOneMinusSrcAlpha
+	BlendDstAlpha
+	BlendOneMinusDstAlpha
+)
+
+type BlendEquation int
+
+const (
+	_ BlendEquation = iota
+	BlendAdd
+	BlendSubtract
+	BlendReverseSubtract
+	BlendMin
+	BlendMax
+)
+
+// BlendMode specifies how fragment colors are combined with the colors
+// already in the color buffer, as in glBlendFuncSeparate and
+// glBlendEquationSeparate.
+type BlendMode struct {
+	SrcColor      BlendFactor
+	DstColor      BlendFactor
+	SrcAlpha      BlendFactor
+	DstAlpha      BlendFactor
+	ColorEquation BlendEquation
+	AlphaEquation BlendEquation
+}
+
+var (
+	BlendModeAlpha    = BlendMode{BlendSrcAlpha, BlendOneMinusSrcAlpha, BlendOne, BlendOneMinusSrcAlpha, BlendAdd, BlendAdd}
+	BlendModeAdditive = BlendMode{BlendSrcAlpha, BlendOne, BlendOne, BlendOne, BlendAdd, BlendAdd}
+	BlendModeMultiply = BlendMode{BlendDstColor, BlendOneMinusSrcAlpha, BlendOne, BlendOneMinusSrcAlpha, BlendAdd, BlendAdd}
+	BlendModeScreen   = BlendMode{BlendOne, BlendOneMinusSrcColor, BlendOne, BlendOneMinusSrcAlpha, BlendAdd, BlendAdd}
+	BlendModeMin      = BlendMode{BlendOne, BlendOne, BlendOne, BlendOne, BlendMin, BlendMin}
+	BlendModeMax      = BlendMode{BlendOne, BlendOne, BlendOne, BlendOne, BlendMax, BlendMax}
+)
+
+func blendFactor(f BlendFactor, src, dst Color) Color {
+	switch f {
+	case BlendZero:
+		return Color{}
+	case BlendSrcColor:
+		return src
+	case BlendOneMinusSrcColor:
+		return White.Sub(src)
+	case BlendDstColor:
+		return dst
+	case BlendOneMinusDstColor:
+		return White.Sub(dst)
+	case BlendSrcAlpha:
+		return Color{src.A, src.A, src.A, src.A}
+	case BlendOneMinusSrcAlpha:
+		a := 1 - src.A
+		return Color{a, a, a, a}
+	case BlendDstAlpha:
+		return Color{dst.A, dst.A, dst.A, dst.A}
+	case BlendOneMinusDstAlpha:
+		a := 1 - dst.A
+		return Color{a, a, a, a}
+	}
+	return White
+}
+
+func blendEquation(e BlendEquation, s, d, sf, df float64) float64 {
+	switch e {
+	case BlendSubtract:
+		return s*sf - d*df
+	case BlendReverseSubtract:
+		return d*df - s*sf
+	case BlendMin:
+		return math.Min(s, d)
+	case BlendMax:
+		return math.Max(s, d)
+	}
+	return s*sf + d*df
+}
+
+// Blend combines a source (fragment) color with a destination color.
+func (m BlendMode) Blend(src, dst Color) Color {
+	sc := blendFactor(m.SrcColor, src, dst)
+	dc := blendFactor(m.DstColor, src, dst)
+	sa := blendFactor(m.SrcAlpha, src, dst)
+	da := blendFactor(m.DstAlpha, src, dst)
+	ce := m.ColorEquation
+	return Color{
+		blendEquation(ce, src.R, dst.R, sc.R, dc.R),
+		blendEquation(ce, src.G, dst.G, sc.G, dc.G),
+		blendEquation(ce, src.B, dst.B, sc.B, dc.B),
+		blendEquation(m.AlphaEquation, src.A, dst.A, sa.A, da.A),
+	}
+}
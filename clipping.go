@@ -1,5 +1,7 @@
 package fauxgl
 
+import "math"
+
 var clipPlanes = []clipPlane{
 	{VectorW{1, 0, 0, 1}, VectorW{-1, 0, 0, 1}},
 	{VectorW{-1, 0, 0, 1}, VectorW{1, 0, 0, 1}},
@@ -13,6 +15,35 @@ type clipPlane struct {
 	P, N VectorW
 }
 
+// clipPlanesForBounds returns clip planes that limit x and y to the
+// specified normalized device coordinate ranges, plus the near and far planes.
+func clipPlanesForBounds(x0, y0, x1, y1 float64) []clipPlane {
+	return []clipPlane{
+		{VectorW{x0, 0, 0, 1}, VectorW{1, 0, 0, -x0}},
+		{VectorW{x1, 0, 0, 1}, VectorW{-1, 0, 0, x1}},
+		{VectorW{0, y0, 0, 1}, VectorW{0, 1, 0, -y0}},
+		{VectorW{0, y1, 0, 1}, VectorW{0, -1, 0, y1}},
+		clipPlanes[4],
+		clipPlanes[5],
+	}
+}
+
+// WorldClipPlane returns clip space plane coefficients, suitable for
+// Context.ClipPlanes, for the plane through point with the specified normal.
+// matrix is the matrix used by the shader to transform positions to clip
+// space. Geometry on the side of the plane that normal points to is kept.
+func WorldClipPlane(point, normal Vector, matrix Matrix) VectorW {
+	n := normal.Normalize()
+	p := VectorW{n.X, n.Y, n.Z, -n.Dot(point)}
+	m := matrix.Inverse()
+	return VectorW{
+		p.X*m.X00 + p.Y*m.X10 + p.Z*m.X20 + p.W*m.X30,
+		p.X*m.X01 + p.Y*m.X11 + p.Z*m.X21 + p.W*m.X31,
+		p.X*m.X02 + p.Y*m.X12 + p.Z*m.X22 + p.W*m.X32,
+		p.X*m.X03 + p.Y*m.X13 + p.Z*m.X23 + p.W*m.X33,
+	}
+}
+
 func (p clipPlane) pointInFront(v VectorW) bool {
 	return v.Sub(p.P).Dot(p.N) > 0
 }
@@ -52,6 +83,10 @@ func sutherlandHodgman(points []VectorW, planes []clipPlane) []VectorW {
 }
 
 func ClipTriangle(t *Triangle) []*Triangle {
+	return clipTriangle(t, clipPlanes)
+}
+
+func clipTriangle(t *Triangle, planes []clipPlane) []*Triangle {
 	w1 := t.V1.Output
 	w2 := t.V2.Output
 	w3 := t.V3.Output
@@ -59,7 +94,7 @@ func ClipTriangle(t *Triangle) []*Triangle {
 	p2 := w2.Vector()
 	p3 := w3.Vector()
 	points := []VectorW{w1, w2, w3}
-	newPoints := sutherlandHodgman(points, clipPlanes)
+	newPoints := sutherlandHodgman(points, planes)
 	var result []*Triangle
 	for i := 2; i < len(newPoints); i++ {
 		b1 := Barycentric(p1, p2, p3, newPoints[0].Vector())
@@ -74,25 +109,38 @@ func ClipTriangle(t *Triangle) []*Triangle {
 }
 
 func ClipLine(l *Line) *Line {
-	// TODO: interpolate vertex attributes when clipped
+	return clipLine(l, clipPlanes)
+}
+
+func clipLine(l *Line, planes []clipPlane) *Line {
 	w1 := l.V1.Output
 	w2 := l.V2.Output
-	for _, plane := range clipPlanes {
-		f1 := plane.pointInFront(w1)
-		f2 := plane.pointInFront(w2)
+	t1, t2 := 0.0, 1.0
+	for _, plane := range planes {
+		d1 := w1.Sub(plane.P).Dot(plane.N)
+		d2 := w2.Sub(plane.P).Dot(plane.N)
+		f1 := d1 > 0
+		f2 := d2 > 0
 		if f1 && f2 {
 			continue
 		} else if f1 {
-			w2 = plane.intersectSegment(w1, w2)
+			t2 = math.Min(t2, d1/(d1-d2))
 		} else if f2 {
-			w1 = plane.intersectSegment(w2, w1)
+			t1 = math.Max(t1, d1/(d1-d2))
 		} else {
 			return nil
 		}
 	}
+	if t1 > t2 {
+		return nil
+	}
 	v1 := l.V1
 	v2 := l.V2
-	v1.Output = w1
-	v2.Output = w2
+	if t1 > 0 {
+		v1 = InterpolateVertexes(l.V1, l.V2, l.V2, VectorW{1 - t1, t1, 0, 1})
+	}
+	if t2 < 1 {
+		v2 = InterpolateVertexes(l.V1, l.V2, l.V2, VectorW{1 - t2, t2, 0, 1})
+	}
 	return NewLine(v1, v2)
 }
@@ -117,3 +117,25 @@ func (a Color) Min(b Color) Color {
 func (a Color) Max(b Color) Color {
 	return Color{math.Max(a.R, b.R), math.Max(a.G, b.G), math.Max(a.B, b.B), math.Max(a.A, b.A)}
 }
+
+// Linear converts an sRGB encoded color to linear. Alpha is unchanged.
+func (a Color) Linear() Color {
+	f := func(x float64) float64 {
+		if x <= 0.04045 {
+			return x / 12.92
+		}
+		return math.Pow((x+0.055)/1.055, 2.4)
+	}
+	return Color{f(a.R), f(a.G), f(a.B), a.A}
+}
+
+// SRGB converts a linear color to sRGB encoding. Alpha is unchanged.
+func (a Color) SRGB() Color {
+	f := func(x float64) float64 {
+		if x <= 0.0031308 {
+			return x * 12.92
+		}
+		return 1.055*math.Pow(x, 1/2.4) - 0.055
+	}
+	return Color{f(a.R), f(a.G), f(a.B), a.A}
+}
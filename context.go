@@ -1,11 +1,11 @@
 package fauxgl
 
 import (
+	"context"
 	"image"
 	"image/color"
 	"math"
 	"runtime"
-	"sync"
 )
 
 type Face int
@@ -25,6 +25,40 @@ const (
 	CullBack
 )
 
+type Compare int
+
+const (
+	_ Compare = iota
+	CompareNever
+	CompareLess
+	CompareLessEqual
+	CompareGreater
+	CompareGreaterEqual
+	CompareEqual
+	CompareNotEqual
+	CompareAlways
+)
+
+func (c Compare) compare(a, b float64) bool {
+	switch c {
+	case CompareNever:
+		return false
+	case CompareLess:
+		return a < b
+	case CompareLessEqual:
+		return a <= b
+	case CompareGreater:
+		return a > b
+	case CompareGreaterEqual:
+		return a >= b
+	case CompareEqual:
+		return a == b
+	case CompareNotEqual:
+		return a != b
+	}
+	return true
+}
+
 type RasterizeInfo struct {
 	TotalPixels   uint64
 	UpdatedPixels uint64
@@ -38,49 +72,122 @@ func (info RasterizeInfo) Add(other RasterizeInfo) RasterizeInfo {
 }
 
 type Context struct {
-	Width        int
-	Height       int
-	ColorBuffer  *image.NRGBA
-	DepthBuffer  []float64
-	ClearColor   Color
-	Shader       Shader
-	ReadDepth    bool
-	WriteDepth   bool
-	WriteColor   bool
-	AlphaBlend   bool
-	Wireframe    bool
-	FrontFace    Face
-	Cull         Cull
-	LineWidth    float64
-	DepthBias    float64
-	screenMatrix Matrix
-	locks        []sync.Mutex
+	Width            int
+	Height           int
+	Samples          int
+	ColorBuffer      *image.NRGBA
+	SampleBuffer     []Color
+	DepthBuffer      []float64
+	StencilBuffer    []uint8
+	IDBuffer         []PixelID
+	RenderTargets    []*RenderTarget
+	ClearColor       Color
+	Shader           Shader
+	ReadDepth        bool
+	WriteDepth       bool
+	WriteColor       bool
+	AlphaBlend       bool
+	OrderIndependent bool
+	BlendMode        BlendMode
+	ToneMap          ToneMap
+	Wireframe        bool
+	FrontFace        Face
+	Cull             Cull
+	LineWidth        float64
+	LineSmooth       bool
+	LineCap          LineCap
+	LineJoin         LineJoin
+	LineMiterLimit   float64
+	LineDash         []float64
+	LineDashOffset   float64
+	PointSize        float64
+	PointShape       PointShape
+	PointSmooth      bool
+	PointSprite      bool
+	PointWorldSize   bool
+	DepthBias        float64
+	MeshID           int
+	DepthFunc        Compare
+	ClearDepth       float64
+	StencilTest      bool
+	StencilFunc      Compare
+	StencilRef       uint8
+	StencilMask      uint8
+	StencilWriteMask uint8
+	StencilFail      StencilOp
+	StencilDepthFail StencilOp
+	StencilPass      StencilOp
+	ClipPlanes       []VectorW
+	ScissorTest      bool
+	Scissor          image.Rectangle
+	Workers          int
+	Progress         func(done, total int)
+	viewport         image.Rectangle
+	screenMatrix     Matrix
+	samples          []Vector
+	hdr              bool
+	outputs          []*RenderTarget
+	fragments        [][]fragment
+	bounds           image.Rectangle
+	clipPlanes       []clipPlane
 }
 
 func NewContext(width, height int) *Context {
+	return NewMultisampleContext(width, height, 1)
+}
+
+// NewMultisampleContext creates a context that stores coverage and depth for
+// the specified number of samples per pixel (1, 2, 4, 8 or 16). The fragment
+// shader still runs once per pixel. Call Resolve (or Image) to average the
+// samples into the ColorBuffer.
+func NewMultisampleContext(width, height, samples int) *Context {
 	dc := &Context{}
 	dc.Width = width
 	dc.Height = height
+	dc.Samples = samples
 	dc.ColorBuffer = image.NewNRGBA(image.Rect(0, 0, width, height))
-	dc.DepthBuffer = make([]float64, width*height)
+	if samples > 1 {
+		dc.SampleBuffer = make([]Color, width*height*samples)
+	}
+	dc.DepthBuffer = make([]float64, width*height*samples)
+	dc.StencilBuffer = make([]uint8, width*height*samples)
 	dc.ClearColor = Transparent
 	dc.Shader = NewSolidColorShader(Identity(), Color{1, 0, 1, 1})
 	dc.ReadDepth = true
 	dc.WriteDepth = true
 	dc.WriteColor = true
 	dc.AlphaBlend = true
+	dc.BlendMode = BlendModeAlpha
 	dc.Wireframe = false
 	dc.FrontFace = FaceCCW
 	dc.Cull = CullBack
 	dc.LineWidth = 2
+	dc.LineSmooth = false
+	dc.LineCap = LineCapSquare
+	dc.LineJoin = LineJoinMiter
+	dc.LineMiterLimit = 4
+	dc.PointSize = 4
+	dc.PointShape = PointShapeCircle
 	dc.DepthBias = 0
-	dc.screenMatrix = Screen(width, height)
-	dc.locks = make([]sync.Mutex, 256)
+	dc.DepthFunc = CompareLessEqual
+	dc.ClearDepth = math.MaxFloat64
+	dc.StencilTest = false
+	dc.StencilFunc = CompareAlways
+	dc.StencilMask = 0xff
+	dc.StencilWriteMask = 0xff
+	dc.StencilFail = StencilKeep
+	dc.StencilDepthFail = StencilKeep
+	dc.StencilPass = StencilKeep
+	dc.Workers = runtime.NumCPU()
+	dc.SetViewport(0, 0, width, height)
+	dc.samples = samplePattern(samples)
 	dc.ClearDepthBuffer()
 	return dc
 }
 
 func (dc *Context) Image() image.Image {
+	dc.ResolveTransparency()
+	dc.Resolve()
 	return dc.ColorBuffer
 }
 
@@ -88,7 +195,7 @@ func (dc *Context) DepthImage() image.Image {
 	lo := math.MaxFloat64
 	hi := -math.MaxFloat64
 	for _, d := range dc.DepthBuffer {
-		if d == math.MaxFloat64 {
+		if d == dc.ClearDepth {
 			continue
 		}
 		if d < lo {
@@ -103,9 +210,9 @@ func (dc *Context) DepthImage() image.Image {
 	var i int
 	for y := 0; y < dc.Height; y++ {
 		for x := 0; x < dc.Width; x++ {
-			d := dc.DepthBuffer[i]
+			d := dc.DepthBuffer[i*dc.Samples]
 			t := (d - lo) / (hi - lo)
-			if d == math.MaxFloat64 {
+			if d == dc.ClearDepth {
 				t = 1
 			}
 			c := color.Gray16{uint16(t * 0xffff)}
@@ -128,6 +235,12 @@ func (dc *Context) ClearColorBufferWith(color Color) {
 			i += 4
 		}
 	}
+	for i := range dc.SampleBuffer {
+		dc.SampleBuffer[i] = color
+	}
+	for i := range dc.fragments {
+		dc.fragments[i] = dc.fragments[i][:0]
+	}
 }
 
 func (dc *Context) ClearColorBuffer() {
@@ -141,146 +254,323 @@ func (dc *Context) ClearDepthBufferWith(value float64) {
 }
 
 func (dc *Context) ClearDepthBuffer() {
-	dc.ClearDepthBufferWith(math.MaxFloat64)
+	dc.ClearDepthBufferWith(dc.ClearDepth)
 }
 
 func edge(a, b, c Vector) float64 {
 	return (b.X-c.X)*(a.Y-c.Y) - (b.Y-c.Y)*(a.X-c.X)
 }
 
-func (dc *Context) rasterize(v0, v1, v2 Vertex, s0, s1, s2 Vector) RasterizeInfo {
+func (dc *Context) rasterize(t *rasterTriangle, bounds image.Rectangle) RasterizeInfo {
 	var info RasterizeInfo
+	v0, v1, v2 := t.v0, t.v1, t.v2
+	s0, s1, s2 := t.s0, t.s1, t.s2
+
+	// fixed point positions
+	fx0, fy0 := fixed(s0.X), fixed(s0.Y)
+	fx1, fy1 := fixed(s1.X), fixed(s1.Y)
+	fx2, fy2 := fixed(s2.X), fixed(s2.Y)
+
+	// twice the area; setup orders vertices so that it is positive
+	area := (fx1-fx2)*(fy0-fy2) - (fy1-fy2)*(fx0-fx2)
+	if area <= 0 {
+		return info
+	}
 
-	// integer bounding box
-	min := s0.Min(s1.Min(s2)).Floor()
-	max := s0.Max(s1.Max(s2)).Ceil()
-	x0 := int(min.X)
-	x1 := int(max.X)
-	y0 := int(min.Y)
-	y1 := int(max.Y)
-
-	// forward differencing variables
-	p := Vector{float64(x0) + 0.5, float64(y0) + 0.5, 0}
-	w00 := edge(s1, s2, p)
-	w01 := edge(s2, s0, p)
-	w02 := edge(s0, s1, p)
-	a01 := s1.Y - s0.Y
-	b01 := s0.X - s1.X
-	a12 := s2.Y - s1.Y
-	b12 := s1.X - s2.X
-	a20 := s0.Y - s2.Y
-	b20 := s2.X - s0.X
+	// integer bounding box, limited to bounds
+	x0 := MaxInt(int(floorDiv(minInt64(fx0, minInt64(fx1, fx2)), subPixels)), bounds.Min.X)
+	x1 := MinInt(int(ceilDiv(maxInt64(fx0, maxInt64(fx1, fx2)), subPixels)), bounds.Max.X-1)
+	y0 := MaxInt(int(floorDiv(minInt64(fy0, minInt64(fy1, fy2)), subPixels)), bounds.Min.Y)
+	y1 := MinInt(int(ceilDiv(maxInt64(fy0, maxInt64(fy1, fy2)), subPixels)), bounds.Max.Y-1)
+	if x0 > x1 || y0 > y1 {
+		return info
+	}
+
+	// edge function increments per subpixel
+	a01 := fy1 - fy0
+	b01 := fx0 - fx1
+	a12 := fy2 - fy1
+	b12 := fx1 - fx2
+	a20 := fy0 - fy2
+	b20 := fx2 - fx0
+
+	// edge functions at the first pixel center, biased by the top-left rule
+	// so that samples exactly on an edge are only covered by one triangle
+	px := int64(x0)*subPixels + subPixels/2
+	py := int64(y0)*subPixels + subPixels/2
+	w00 := (fx2-px)*(fy1-py) - (fy2-py)*(fx1-px) + topLeftBias(a12, b12)
+	w01 := (fx0-px)*(fy2-py) - (fy0-py)*(fx2-px) + topLeftBias(a20, b20)
+	w02 := (fx1-px)*(fy0-py) - (fy1-py)*(fx0-px) + topLeftBias(a01, b01)
 
 	// reciprocals
-	ra := 1 / edge(s0, s1, s2)
+	ra := 1 / float64(area)
 	r0 := 1 / v0.Output.W
 	r1 := 1 / v1.Output.W
 	r2 := 1 / v2.Output.W
-	ra12 := 1 / a12
-	ra20 := 1 / a20
-	ra01 := 1 / a01
+
+	// sample offsets in subpixels, and the largest change in each edge
+	// function from the pixel center to any sample
+	var offsets [maxSamples][2]int64
+	var m0, m1, m2 int64
+	for s, o := range dc.samples {
+		ox, oy := fixed(o.X), fixed(o.Y)
+		offsets[s] = [2]int64{ox, oy}
+		if s == 0 || a12*ox+b12*oy > m0 {
+			m0 = a12*ox + b12*oy
+		}
+		if s == 0 || a20*ox+b20*oy > m1 {
+			m1 = a20*ox + b20*oy
+		}
+		if s == 0 || a01*ox+b01*oy > m2 {
+			m2 = a01*ox + b01*oy
+		}
+	}
+
+	// per-sample coverage and depth
+	n := len(dc.samples)
+	var zs [maxSamples]float64
+
+	// interpolated vertex, reused for each fragment
+	var v Vertex
+
+	// screen space derivatives, for shaders that use them
+	derivative, _ := dc.Shader.(DerivativeShader)
+	if derivative != nil && !derivative.UsesDerivatives() {
+		derivative = nil
+	}
+	var dx, dy Vertex
+	var scratch [3]Vertex
+
+	// additional fragment outputs
+	shader, _ := dc.Shader.(MultiShader)
+	var outputs []Color
+	if shader != nil {
+		outputs = make([]Color, len(dc.outputs))
+	}
 
 	// iterate over all pixels in bounding box
 	for y := y0; y <= y1; y++ {
-		var d float64
-		d0 := -w00 * ra12
-		d1 := -w01 * ra20
-		d2 := -w02 * ra01
-		if w00 < 0 && d0 > d {
-			d = d0
+		// skip pixels left of the triangle
+		var d int64
+		if w00+m0 < 0 && a12 > 0 {
+			d = maxInt64(d, ceilDiv(-w00-m0, a12*subPixels))
 		}
-		if w01 < 0 && d1 > d {
-			d = d1
+		if w01+m1 < 0 && a20 > 0 {
+			d = maxInt64(d, ceilDiv(-w01-m1, a20*subPixels))
 		}
-		if w02 < 0 && d2 > d {
-			d = d2
+		if w02+m2 < 0 && a01 > 0 {
+			d = maxInt64(d, ceilDiv(-w02-m2, a01*subPixels))
 		}
-		d = float64(int(d))
-		if d < 0 {
-			// occurs in pathological cases
-			d = 0
-		}
-		w0 := w00 + a12*d
-		w1 := w01 + a20*d
-		w2 := w02 + a01*d
+		w0 := w00 + a12*subPixels*d
+		w1 := w01 + a20*subPixels*d
+		w2 := w02 + a01*subPixels*d
 		wasInside := false
 		for x := x0 + int(d); x <= x1; x++ {
-			b0 := w0 * ra
-			b1 := w1 * ra
-			b2 := w2 * ra
-			w0 += a12
-			w1 += a20
-			w2 += a01
-			// check if inside triangle
-			if b0 < 0 || b1 < 0 || b2 < 0 {
-				if wasInside {
+			cw0, cw1, cw2 := w0, w1, w2
+			w0 += a12 * subPixels
+			w1 += a20 * subPixels
+			w2 += a01 * subPixels
+			// check which samples are inside triangle
+			var covered, passed uint32
+			var b0, b1, b2 float64
+			for s := 0; s < n; s++ {
+				ox, oy := offsets[s][0], offsets[s][1]
+				sw0 := cw0 + a12*ox + b12*oy
+				sw1 := cw1 + a20*ox + b20*oy
+				sw2 := cw2 + a01*ox + b01*oy
+				if sw0 < 0 || sw1 < 0 || sw2 < 0 {
+					continue
+				}
+				sb0 := float64(sw0) * ra
+				sb1 := float64(sw1) * ra
+				sb2 := float64(sw2) * ra
+				if covered == 0 {
+					// interpolate at the first covered sample
+					b0, b1, b2 = sb0, sb1, sb2
+				}
+				covered |= 1 << uint(s)
+				zs[s] = sb0*s0.Z + sb1*s1.Z + sb2*s2.Z
+			}
+			if covered == 0 {
+				// with one sample the covered pixels of a row are
+				// contiguous; otherwise the row ends once some edge is
+				// negative at every sample and decreasing to the right
+				if n == 1 && wasInside {
+					break
+				}
+				if (cw0+m0 < 0 && a12 < 0) || (cw1+m1 < 0 && a20 < 0) || (cw2+m2 < 0 && a01 < 0) {
 					break
 				}
 				continue
 			}
 			wasInside = true
-			// check depth buffer for early abort
-			i := y*dc.Width + x
-			if i < 0 || i >= len(dc.DepthBuffer) {
-				// TODO: clipping roundoff error; fix
-				// TODO: could also be from fat lines going off screen
-				continue
+			// coverage of styled lines
+			coverage := 1.0
+			if t.line != nil {
+				coverage = dc.lineCoverage(t.line, float64(x)+0.5, float64(y)+0.5)
+				if coverage <= 0 {
+					continue
+				}
+			}
+			// prefer the pixel center when it is covered
+			if cw0 >= 0 && cw1 >= 0 && cw2 >= 0 {
+				b0, b1, b2 = float64(cw0)*ra, float64(cw1)*ra, float64(cw2)*ra
 			}
+			// stencil and depth tests
+			i := y*dc.Width + x
 			info.TotalPixels++
-			z := b0*s0.Z + b1*s1.Z + b2*s2.Z
-			bz := z + dc.DepthBias
-			if dc.ReadDepth && bz > dc.DepthBuffer[i] { // safe w/out lock?
+			var stencilFailed uint32
+			for s := 0; s < n; s++ {
+				bit := uint32(1) << uint(s)
+				if covered&bit == 0 {
+					continue
+				}
+				j := i*n + s
+				if dc.StencilTest && !dc.stencilTest(j) {
+					stencilFailed |= bit
+					continue
+				}
+				if dc.ReadDepth && !dc.DepthFunc.compare(zs[s]+dc.DepthBias, dc.DepthBuffer[j]) {
+					continue
+				}
+				passed |= bit
+			}
+			var color Color
+			if passed != 0 {
+				// perspective-correct interpolation of vertex data
+				b := VectorW{b0 * r0, b1 * r1, b2 * r2, 0}
+				b.W = 1 / (b.X + b.Y + b.Z)
+				interpolateVertexes(&v, v0, v1, v2, b)
+				// invoke fragment shader
+				if shader != nil {
+					color = shader.FragmentOutputs(v, outputs)
+				} else if derivative != nil {
+					quadDerivatives(&dx, &dy, &scratch, t, x, y,
+						float64(cw0)/subPixels/subPixels,
+						float64(cw1)/subPixels/subPixels,
+						float64(cw2)/subPixels/subPixels)
+					color = derivative.FragmentDerivatives(v, dx, dy)
+				} else {
+					color = dc.Shader.Fragment(v)
+				}
+				if color == Discard {
+					continue
+				}
+				if coverage < 1 {
+					color.A *= coverage
+				}
+			}
+			// update stencil buffer
+			if dc.StencilTest {
+				for s := 0; s < n; s++ {
+					bit := uint32(1) << uint(s)
+					if covered&bit == 0 {
+						continue
+					}
+					op := dc.StencilPass
+					if stencilFailed&bit != 0 {
+						op = dc.StencilFail
+					} else if passed&bit == 0 {
+						op = dc.StencilDepthFail
+					}
+					dc.updateStencil(i*n+s, op)
+				}
+			}
+			if passed == 0 {
 				continue
 			}
-			// perspective-correct interpolation of vertex data
-			b := VectorW{b0 * r0, b1 * r1, b2 * r2, 0}
-			b.W = 1 / (b.X + b.Y + b.Z)
-			v := InterpolateVertexes(v0, v1, v2, b)
-			// invoke fragment shader
-			color := dc.Shader.Fragment(v)
-			if color == Discard {
+			info.UpdatedPixels++
+			// defer translucent fragments to ResolveTransparency
+			if dc.OrderIndependent && color.A < 1 {
+				if dc.WriteColor {
+					dc.addFragment(i, color, passed, &zs)
+				}
 				continue
 			}
-			// update buffers atomically
-			lock := &dc.locks[(x+y)&255]
-			lock.Lock()
-			// check depth buffer again
-			if bz <= dc.DepthBuffer[i] || !dc.ReadDepth {
-				info.UpdatedPixels++
+			// update depth and color buffers
+			if dc.WriteColor {
+				for k, target := range dc.outputs {
+					target.Buffer[i] = outputs[k]
+				}
+			}
+			if dc.IDBuffer != nil && dc.WriteColor {
+				dc.IDBuffer[i] = PixelID{int32(dc.MeshID), t.index}
+			}
+			for s := 0; s < n; s++ {
+				if passed&(1<<uint(s)) == 0 {
+					continue
+				}
+				j := i*n + s
 				if dc.WriteDepth {
-					// update depth buffer
-					dc.DepthBuffer[i] = z
+					dc.DepthBuffer[j] = zs[s]
 				}
 				if dc.WriteColor {
-					// update color buffer
-					if dc.AlphaBlend && color.A < 1 {
-						sr, sg, sb, sa := color.NRGBA().RGBA()
-						a := (0xffff - sa) * 0x101
-						j := dc.ColorBuffer.PixOffset(x, y)
-						dr := &dc.ColorBuffer.Pix[j+0]
-						dg := &dc.ColorBuffer.Pix[j+1]
-						db := &dc.ColorBuffer.Pix[j+2]
-						da := &dc.ColorBuffer.Pix[j+3]
-						*dr = uint8((uint32(*dr)*a/0xffff + sr) >> 8)
-						*dg = uint8((uint32(*dg)*a/0xffff + sg) >> 8)
-						*db = uint8((uint32(*db)*a/0xffff + sb) >> 8)
-						*da = uint8((uint32(*da)*a/0xffff + sa) >> 8)
+					if dc.SampleBuffer != nil {
+						dc.writeSample(j, color)
 					} else {
-						dc.ColorBuffer.SetNRGBA(x, y, color.NRGBA())
+						dc.writePixel(x, y, color)
 					}
 				}
 			}
-			lock.Unlock()
 		}
-		w00 += b12
-		w01 += b20
-		w02 += b01
+		w00 += b12 * subPixels
+		w01 += b20 * subPixels
+		w02 += b01 * subPixels
 	}
 
 	return info
 }
 
-func (dc *Context) line(v0, v1 Vertex, s0, s1 Vector) RasterizeInfo {
+func (dc *Context) writePixel(x, y int, color Color) {
+	if !dc.AlphaBlend {
+		dc.ColorBuffer.SetNRGBA(x, y, color.NRGBA())
+	} else if dc.BlendMode != BlendModeAlpha {
+		j := dc.ColorBuffer.PixOffset(x, y)
+		p := dc.ColorBuffer.Pix[j : j+4]
+		const d = 0xff
+		dst := Color{float64(p[0]) / d, float64(p[1]) / d, float64(p[2]) / d, float64(p[3]) / d}
+		src := color.Min(White).Max(Transparent)
+		dc.ColorBuffer.SetNRGBA(x, y, dc.BlendMode.Blend(src, dst).NRGBA())
+	} else if color.A < 1 {
+		sr, sg, sb, sa := color.NRGBA().RGBA()
+		a := (0xffff - sa) * 0x101
+		j := dc.ColorBuffer.PixOffset(x, y)
+		dr := &dc.ColorBuffer.Pix[j+0]
+		dg := &dc.ColorBuffer.Pix[j+1]
+		db := &dc.ColorBuffer.Pix[j+2]
+		da := &dc.ColorBuffer.Pix[j+3]
+		*dr = uint8((uint32(*dr)*a/0xffff + sr) >> 8)
+		*dg = uint8((uint32(*dg)*a/0xffff + sg) >> 8)
+		*db = uint8((uint32(*db)*a/0xffff + sb) >> 8)
+		*da = uint8((uint32(*da)*a/0xffff + sa) >> 8)
+	} else {
+		dc.ColorBuffer.SetNRGBA(x, y, color.NRGBA())
+	}
+}
+
+func (dc *Context) writeSample(j int, color Color) {
+	if dc.AlphaBlend && (dc.BlendMode != BlendModeAlpha || color.A < 1) {
+		if !dc.hdr {
+			color = color.Min(White).Max(Transparent)
+		}
+		color = dc.BlendMode.Blend(color, dc.SampleBuffer[j])
+	}
+	if !dc.hdr {
+		color = color.Min(White).Max(Transparent)
+	}
+	dc.SampleBuffer[j] = color
+}
+
+// project returns the screen space position of a shaded vertex.
+func (dc *Context) project(v Vertex) Vector {
+	return dc.screenMatrix.MulPosition(v.Output.DivScalar(v.Output.W).Vector())
+}
+
+func (dc *Context) line(v0, v1 Vertex, s0, s1 Vector, buf []rasterTriangle) []rasterTriangle {
+	if dc.styledLines() {
+		length := math.Hypot(s1.X-s0.X, s1.Y-s0.Y)
+		return dc.styledLine(v0, v1, s0, s1, dc.LineCap, dc.LineCap, 0, length, buf)
+	}
 	n := s1.Sub(s0).Perpendicular().MulScalar(dc.LineWidth / 2)
 	s0 = s0.Add(s0.Sub(s1).Normalize().MulScalar(dc.LineWidth / 2))
 	s1 = s1.Add(s1.Sub(s0).Normalize().MulScalar(dc.LineWidth / 2))
@@ -288,19 +578,19 @@ func (dc *Context) line(v0, v1 Vertex, s0, s1 Vector) RasterizeInfo {
 	s01 := s0.Sub(n)
 	s10 := s1.Add(n)
 	s11 := s1.Sub(n)
-	info1 := dc.rasterize(v1, v0, v0, s11, s01, s00)
-	info2 := dc.rasterize(v1, v1, v0, s10, s11, s00)
-	return info1.Add(info2)
+	buf = append(buf, rasterTriangle{v1, v0, v0, s11, s01, s00, 0, nil})
+	buf = append(buf, rasterTriangle{v1, v1, v0, s10, s11, s00, 0, nil})
+	return buf
 }
 
-func (dc *Context) wireframe(v0, v1, v2 Vertex, s0, s1, s2 Vector) RasterizeInfo {
-	info1 := dc.line(v0, v1, s0, s1)
-	info2 := dc.line(v1, v2, s1, s2)
-	info3 := dc.line(v2, v0, s2, s0)
-	return info1.Add(info2).Add(info3)
+func (dc *Context) wireframe(v0, v1, v2 Vertex, s0, s1, s2 Vector, buf []rasterTriangle) []rasterTriangle {
+	buf = dc.line(v0, v1, s0, s1, buf)
+	buf = dc.line(v1, v2, s1, s2, buf)
+	buf = dc.line(v2, v0, s2, s0, buf)
+	return buf
 }
 
-func (dc *Context) drawClippedLine(v0, v1 Vertex) RasterizeInfo {
+func (dc *Context) setupClippedLine(v0, v1 Vertex, buf []rasterTriangle) []rasterTriangle {
 	// normalized device coordinates
 	ndc0 := v0.Output.DivScalar(v0.Output.W).Vector()
 	ndc1 := v1.Output.DivScalar(v1.Output.W).Vector()
@@ -309,11 +599,11 @@ func (dc *Context) drawClippedLine(v0, v1 Vertex) RasterizeInfo {
 	s0 := dc.screenMatrix.MulPosition(ndc0)
 	s1 := dc.screenMatrix.MulPosition(ndc1)
 
-	// rasterize
-	return dc.line(v0, v1, s0, s1)
+	// expand to triangles
+	return dc.line(v0, v1, s0, s1, buf)
 }
 
-func (dc *Context) drawClippedTriangle(v0, v1, v2 Vertex) RasterizeInfo {
+func (dc *Context) setupClippedTriangle(v0, v1, v2 Vertex, buf []rasterTriangle) []rasterTriangle {
 	// normalized device coordinates
 	ndc0 := v0.Output.DivScalar(v0.Output.W).Vector()
 	ndc1 := v1.Output.DivScalar(v1.Output.W).Vector()
@@ -332,7 +622,7 @@ func (dc *Context) drawClippedTriangle(v0, v1, v2 Vertex) RasterizeInfo {
 		a = -a
 	}
 	if dc.Cull != CullNone && a <= 0 {
-		return RasterizeInfo{}
+		return buf
 	}
 
 	// screen coordinates
@@ -340,100 +630,133 @@ func (dc *Context) drawClippedTriangle(v0, v1, v2 Vertex) RasterizeInfo {
 	s1 := dc.screenMatrix.MulPosition(ndc1)
 	s2 := dc.screenMatrix.MulPosition(ndc2)
 
-	// rasterize
+	// expand to lines if needed
 	if dc.Wireframe {
-		return dc.wireframe(v0, v1, v2, s0, s1, s2)
+		return dc.wireframe(v0, v1, v2, s0, s1, s2, buf)
 	} else {
-		return dc.rasterize(v0, v1, v2, s0, s1, s2)
+		return append(buf, rasterTriangle{v0, v1, v2, s0, s1, s2, 0, nil})
 	}
 }
 
-func (dc *Context) DrawLine(t *Line) RasterizeInfo {
+// setupLine runs the vertex shader on a line and clips it to the viewing
+// volume, appending the screen space triangles that cover it to buf.
+func (dc *Context) setupLine(t *Line, buf []rasterTriangle) []rasterTriangle {
 	// invoke vertex shader
 	v1 := dc.Shader.Vertex(t.V1)
 	v2 := dc.Shader.Vertex(t.V2)
 
-	if v1.Outside() || v2.Outside() {
+	if dc.outside(v1) || dc.outside(v2) {
 		// clip to viewing volume
-		line := ClipLine(NewLine(v1, v2))
+		line := clipLine(NewLine(v1, v2), dc.clipPlanes)
 		if line != nil {
-			return dc.drawClippedLine(line.V1, line.V2)
+			return dc.setupClippedLine(line.V1, line.V2, buf)
 		} else {
-			return RasterizeInfo{}
+			return buf
 		}
 	} else {
 		// no need to clip
-		return dc.drawClippedLine(v1, v2)
+		return dc.setupClippedLine(v1, v2, buf)
 	}
 }
 
-func (dc *Context) DrawTriangle(t *Triangle) RasterizeInfo {
+// setupTriangle runs the vertex shader on a triangle, clips it to the
+// viewing volume and culls it, appending the resulting screen space
+// triangles to buf.
+func (dc *Context) setupTriangle(t *Triangle, buf []rasterTriangle) []rasterTriangle {
 	// invoke vertex shader
 	v1 := dc.Shader.Vertex(t.V1)
 	v2 := dc.Shader.Vertex(t.V2)
 	v3 := dc.Shader.Vertex(t.V3)
+	return dc.setupShadedTriangle(v1, v2, v3, buf)
+}
 
-	if v1.Outside() || v2.Outside() || v3.Outside() {
+// setupShadedTriangle is like setupTriangle for vertices that have already
+// been run through the vertex shader.
+func (dc *Context) setupShadedTriangle(v1, v2, v3 Vertex, buf []rasterTriangle) []rasterTriangle {
+	if dc.outside(v1) || dc.outside(v2) || dc.outside(v3) {
 		// clip to viewing volume
-		triangles := ClipTriangle(NewTriangle(v1, v2, v3))
-		var result RasterizeInfo
+		triangles := clipTriangle(NewTriangle(v1, v2, v3), dc.clipPlanes)
 		for _, t := range triangles {
-			info := dc.drawClippedTriangle(t.V1, t.V2, t.V3)
-			result = result.Add(info)
+			buf = dc.setupClippedTriangle(t.V1, t.V2, t.V3, buf)
 		}
-		return result
+		return buf
 	} else {
 		// no need to clip
-		return dc.drawClippedTriangle(v1, v2, v3)
+		return dc.setupClippedTriangle(v1, v2, v3, buf)
 	}
 }
 
-func (dc *Context) DrawLines(lines []*Line) RasterizeInfo {
-	wn := runtime.NumCPU()
-	ch := make(chan RasterizeInfo, wn)
-	for wi := 0; wi < wn; wi++ {
-		go func(wi int) {
-			var result RasterizeInfo
-			for i, l := range lines {
-				if i%wn == wi {
-					info := dc.DrawLine(l)
-					result = result.Add(info)
-				}
-			}
-			ch <- result
-		}(wi)
+// beginDraw prepares per-draw state before any triangles are rasterized.
+func (dc *Context) beginDraw() {
+	dc.updateBounds()
+	dc.bindRenderTargets()
+	if dc.OrderIndependent && dc.fragments == nil {
+		dc.fragments = make([][]fragment, dc.Width*dc.Height)
 	}
+}
+
+func (dc *Context) rasterizeAll(triangles []rasterTriangle) RasterizeInfo {
 	var result RasterizeInfo
-	for wi := 0; wi < wn; wi++ {
-		result = result.Add(<-ch)
+	for i := range triangles {
+		info := dc.rasterize(&triangles[i], dc.bounds)
+		result = result.Add(info)
 	}
 	return result
 }
 
+func (dc *Context) DrawLine(t *Line) RasterizeInfo {
+	dc.beginDraw()
+	return dc.rasterizeAll(dc.setupLine(t, nil))
+}
+
+func (dc *Context) DrawTriangle(t *Triangle) RasterizeInfo {
+	dc.beginDraw()
+	return dc.rasterizeAll(dc.setupTriangle(t, nil))
+}
+
+func (dc *Context) DrawLines(lines []*Line) RasterizeInfo {
+	info, _ := dc.DrawLinesContext(context.Background(), lines)
+	return info
+}
+
+// DrawLinesContext is like DrawLines but stops early if ctx is canceled,
+// returning ctx.Err().
+func (dc *Context) DrawLinesContext(ctx context.Context, lines []*Line) (RasterizeInfo, error) {
+	return dc.drawBinned(ctx, len(lines), nil, func(i int, buf []rasterTriangle) []rasterTriangle {
+		return dc.setupLine(lines[i], buf)
+	})
+}
+
 func (dc *Context) DrawTriangles(triangles []*Triangle) RasterizeInfo {
-	wn := runtime.NumCPU()
-	ch := make(chan RasterizeInfo, wn)
-	for wi := 0; wi < wn; wi++ {
-		go func(wi int) {
-			var result RasterizeInfo
-			for i, t := range triangles {
-				if i%wn == wi {
-					info := dc.DrawTriangle(t)
-					result = result.Add(info)
-				}
-			}
-			ch <- result
-		}(wi)
-	}
-	var result RasterizeInfo
-	for wi := 0; wi < wn; wi++ {
-		result = result.Add(<-ch)
-	}
-	return result
+	info, _ := dc.DrawTrianglesContext(context.Background(), triangles)
+	return info
+}
+
+// DrawTrianglesContext is like DrawTriangles but stops early if ctx is
+// canceled, returning ctx.Err().
+func (dc *Context) DrawTrianglesContext(ctx context.Context, triangles []*Triangle) (RasterizeInfo, error) {
+	return dc.drawBinned(ctx, len(triangles), nil, func(i int, buf []rasterTriangle) []rasterTriangle {
+		return dc.setupTriangle(triangles[i], buf)
+	})
 }
 
 func (dc *Context) DrawMesh(mesh *Mesh) RasterizeInfo {
-	info1 := dc.DrawTriangles(mesh.Triangles)
-	info2 := dc.DrawLines(mesh.Lines)
-	return info1.Add(info2)
+	info, _ := dc.DrawMeshContext(context.Background(), mesh)
+	return info
+}
+
+// DrawMeshContext is like DrawMesh but stops early if ctx is canceled,
+// returning ctx.Err().
+func (dc *Context) DrawMeshContext(ctx context.Context, mesh *Mesh) (RasterizeInfo, error) {
+	n := len(mesh.Triangles)
+	m := n + len(mesh.Lines)
+	return dc.drawBinned(ctx, m+len(mesh.Points), nil, func(i int, buf []rasterTriangle) []rasterTriangle {
+		if i < n {
+			return dc.setupTriangle(mesh.Triangles[i], buf)
+		}
+		if i < m {
+			return dc.setupLine(mesh.Lines[i-n], buf)
+		}
+		return dc.setupPoint(mesh.Points[i-m], buf)
+	})
 }
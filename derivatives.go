@@ -0,0 +1,78 @@
+package fauxgl
+
+// DerivativeShader is a Shader whose fragment function also receives the
+// screen space derivatives of the interpolated vertex. Fragments are shaded
+// in 2x2 pixel quads: dx is the difference between the vertex interpolated
+// at the right and left pixel centers of the fragment's row of the quad, and
+// dy between the bottom and top pixel centers of its column, with y down.
+// Pixels of the quad outside the primitive are extrapolated, as on GPUs.
+//
+// Computing derivatives costs three extra vertex interpolations per
+// fragment, so FragmentDerivatives is only called while UsesDerivatives
+// returns true; otherwise Fragment is called.
+type DerivativeShader interface {
+	Shader
+	UsesDerivatives() bool
+	FragmentDerivatives(v, dx, dy Vertex) Color
+}
+
+// FlatNormal returns the facing normal of a surface from the screen space
+// derivatives of its positions.
+func FlatNormal(dx, dy Vertex) Vector {
+	return dy.Position.Cross(dx.Position).Normalize()
+}
+
+// interpolateAt stores in dst the perspective-correct interpolation of the
+// triangle's vertices where its edge functions are w0, w1, w2.
+func interpolateAt(dst *Vertex, t *rasterTriangle, w0, w1, w2 float64) {
+	ra := 1 / edge(t.s0, t.s1, t.s2)
+	b := VectorW{w0 * ra / t.v0.Output.W, w1 * ra / t.v1.Output.W, w2 * ra / t.v2.Output.W, 0}
+	b.W = 1 / (b.X + b.Y + b.Z)
+	interpolateVertexes(dst, t.v0, t.v1, t.v2, b)
+}
+
+// quadDerivatives stores in dx and dy the derivatives of the vertex
+// attributes for the pixel at x, y whose edge functions are w0, w1, w2 at its
+// center. scratch holds three vertices used for interpolation.
+func quadDerivatives(dx, dy *Vertex, scratch *[3]Vertex, t *rasterTriangle, x, y int, w0, w1, w2 float64) {
+	a12, b12 := t.s2.Y-t.s1.Y, t.s1.X-t.s2.X
+	a20, b20 := t.s0.Y-t.s2.Y, t.s2.X-t.s0.X
+	a01, b01 := t.s1.Y-t.s0.Y, t.s0.X-t.s1.X
+	// neighbors within the quad
+	ox, oy := 1.0, 1.0
+	if x&1 == 1 {
+		ox = -1
+	}
+	if y&1 == 1 {
+		oy = -1
+	}
+	c, h, v := &scratch[0], &scratch[1], &scratch[2]
+	interpolateAt(c, t, w0, w1, w2)
+	interpolateAt(h, t, w0+a12*ox, w1+a20*ox, w2+a01*ox)
+	interpolateAt(v, t, w0+b12*oy, w1+b20*oy, w2+b01*oy)
+	differenceVertexes(dx, *h, *c, ox)
+	differenceVertexes(dy, *v, *c, oy)
+}
+
+// differenceVertexes stores (a - b) * s in dst, for all vertex attributes.
+func differenceVertexes(dst *Vertex, a, b Vertex, s float64) {
+	dst.Position = a.Position.Sub(b.Position).MulScalar(s)
+	dst.Normal = a.Normal.Sub(b.Normal).MulScalar(s)
+	dst.Tangent = a.Tangent.Sub(b.Tangent).MulScalar(s)
+	dst.Bitangent = a.Bitangent.Sub(b.Bitangent).MulScalar(s)
+	dst.Texture = a.Texture.Sub(b.Texture).MulScalar(s)
+	dst.Color = a.Color.Sub(b.Color).MulScalar(s)
+	dst.Output = a.Output.Sub(b.Output).MulScalar(s)
+	dst.Vectors = dst.Vectors[:0]
+	for i := range a.Vectors {
+		dst.Vectors = append(dst.Vectors, a.Vectors[i].Sub(b.Vectors[i]).MulScalar(s))
+	}
+	dst.Colors = dst.Colors[:0]
+	for i := range a.Colors {
+		dst.Colors = append(dst.Colors, a.Colors[i].Sub(b.Colors[i]).MulScalar(s))
+	}
+	dst.Floats = dst.Floats[:0]
+	for i := range a.Floats {
+		dst.Floats = append(dst.Floats, (a.Floats[i]-b.Floats[i])*s)
+	}
+}
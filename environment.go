@@ -0,0 +1,256 @@
+package fauxgl
+
+import (
+	"math"
+	"runtime"
+)
+
+// EnvironmentTexture is a texture over the sphere of directions, such as a
+// sky or studio surrounding a scene. Directions need not be normalized.
+type EnvironmentTexture interface {
+	DirectionSample(d Vector) Color
+}
+
+// EquirectangularTexture maps longitude to u and latitude to v, with +Y up
+// and u = 0.5 facing +X.
+type EquirectangularTexture struct {
+	Texture Texture
+}
+
+func NewEquirectangularTexture(texture Texture) *EquirectangularTexture {
+	return &EquirectangularTexture{texture}
+}
+
+func (t *EquirectangularTexture) DirectionSample(d Vector) Color {
+	u, v := equirectangularCoordinates(d)
+	return t.Texture.BilinearSample(u, v)
+}
+
+func equirectangularCoordinates(d Vector) (u, v float64) {
+	d = d.Normalize()
+	u = 0.5 + math.Atan2(d.Z, d.X)/(2*math.Pi)
+	v = 0.5 + math.Asin(Clamp(d.Y, -1, 1))/math.Pi
+	// textures wrap v, so keep the bottom row from sampling the top
+	return u, math.Max(v, 1e-9)
+}
+
+func equirectangularDirection(u, v float64) Vector {
+	lng := (u - 0.5) * 2 * math.Pi
+	lat := (v - 0.5) * math.Pi
+	return Vector{math.Cos(lat) * math.Cos(lng), math.Sin(lat), math.Cos(lat) * math.Sin(lng)}
+}
+
+// CubeTexture is a cube map with faces in the order +X, -X, +Y, -Y, +Z, -Z,
+// oriented as in OpenGL.
+type CubeTexture struct {
+	Faces [6]Texture
+}
+
+func NewCubeTexture(px, nx, py, ny, pz, nz Texture) *CubeTexture {
+	return &CubeTexture{[6]Texture{px, nx, py, ny, pz, nz}}
+}
+
+func (t *CubeTexture) DirectionSample(d Vector) Color {
+	a := d.Abs()
+	var face int
+	var sc, tc, ma float64
+	switch {
+	case a.X >= a.Y && a.X >= a.Z:
+		face, sc, tc, ma = 0, -d.Z, -d.Y, d.X
+		if d.X < 0 {
+			face, sc = 1, d.Z
+		}
+	case a.Y >= a.Z:
+		face, sc, tc, ma = 2, d.X, d.Z, d.Y
+		if d.Y < 0 {
+			face, tc = 3, -d.Z
+		}
+	default:
+		face, sc, tc, ma = 4, d.X, -d.Y, d.Z
+		if d.Z < 0 {
+			face, sc = 5, -d.X
+		}
+	}
+	ma = math.Abs(ma)
+	s := (sc/ma + 1) / 2
+	// t runs down the face image, v runs up
+	return t.Faces[face].BilinearSample(s, 1-(tc/ma+1)/2)
+}
+
+// Environment is an environment map prefiltered for image based lighting.
+// Irradiance is stored as second order spherical harmonics, and specular
+// reflections are convolved with the GGX distribution at a range of
+// roughnesses.
+//
+// An Environment is also an ambient Light, lighting surfaces with its
+// irradiance, for shaders other than PBRShader.
+type Environment struct {
+	radiance *MipmapTexture
+	specular []Texture
+	sh       [9]Color
+}
+
+const (
+	environmentWidth   = 512
+	environmentLevels  = 6
+	environmentSamples = 64
+)
+
+// NewEnvironment prefilters an environment texture. This takes a fraction
+// of a second, so environments should be reused between frames.
+func NewEnvironment(texture EnvironmentTexture) *Environment {
+	w, h := environmentWidth, environmentWidth/2
+	base := resampleEnvironment(w, h, texture.DirectionSample)
+	e := &Environment{}
+	e.radiance = base.Mipmap()
+	e.sh = projectSH(e.radiance.Levels[3].(*FloatTexture))
+	e.specular = []Texture{base}
+	for i := 1; i < environmentLevels; i++ {
+		roughness := float64(i) / (environmentLevels - 1)
+		w, h := MaxInt(w>>(i+1), 8), MaxInt(h>>(i+1), 4)
+		level := resampleEnvironment(w, h, func(d Vector) Color {
+			return e.prefilter(d, roughness)
+		})
+		e.specular = append(e.specular, level)
+	}
+	return e
+}
+
+// Sample returns the unfiltered radiance arriving from direction d.
+func (e *Environment) Sample(d Vector) Color {
+	u, v := equirectangularCoordinates(d)
+	return e.specular[0].BilinearSample(u, v)
+}
+
+// Irradiance returns the cosine weighted average radiance arriving at a
+// surface with normal n, that is, the light reflected by a white
+// Lambertian surface.
+func (e *Environment) Irradiance(n Vector) Color {
+	// convolution with the clamped cosine, divided by pi
+	const a0, a1, a2 = 1, 2.0 / 3, 1.0 / 4
+	y := shBasis(n.Normalize())
+	c := e.sh[0].MulScalar(a0 * y[0])
+	for i := 1; i < 4; i++ {
+		c = c.Add(e.sh[i].MulScalar(a1 * y[i]))
+	}
+	for i := 4; i < 9; i++ {
+		c = c.Add(e.sh[i].MulScalar(a2 * y[i]))
+	}
+	return c.Max(Black).Alpha(1)
+}
+
+// Specular returns the radiance reflected in direction r from a surface
+// of the given perceptual roughness, excluding the Fresnel and geometry
+// terms.
+func (e *Environment) Specular(r Vector, roughness float64) Color {
+	u, v := equirectangularCoordinates(r)
+	x := Clamp(roughness, 0, 1) * (environmentLevels - 1)
+	i := int(x)
+	c := e.specular[i].BilinearSample(u, v)
+	if f := x - float64(i); f > 0 {
+		c = c.Lerp(e.specular[i+1].BilinearSample(u, v), f)
+	}
+	return c
+}
+
+func (e *Environment) Illuminate(position, normal Vector) (Vector, Color) {
+	return Vector{}, e.Irradiance(normal)
+}
+
+// prefilter convolves the environment with the GGX distribution around r,
+// assuming the view and normal directions equal r. Samples are importance
+// sampled and read from the mip level matching their solid angle.
+func (e *Environment) prefilter(r Vector, roughness float64) Color {
+	a := roughness * roughness
+	a2 := a * a
+	tx := perpendicular(r)
+	ty := r.Cross(tx)
+	texel := 4 * math.Pi / float64(e.radiance.Width*e.radiance.Height)
+	var sum Color
+	var weight float64
+	for i := 0; i < environmentSamples; i++ {
+		u1, u2 := hammersley(i, environmentSamples)
+		phi := 2 * math.Pi * u1
+		cos := math.Sqrt((1 - u2) / (1 + (a2-1)*u2))
+		sin := math.Sqrt(1 - cos*cos)
+		h := tx.MulScalar(sin * math.Cos(phi)).Add(ty.MulScalar(sin * math.Sin(phi))).Add(r.MulScalar(cos))
+		l := h.MulScalar(2 * r.Dot(h)).Sub(r)
+		nl := r.Dot(l)
+		if nl <= 0 {
+			continue
+		}
+		d := cos*cos*(a2-1) + 1
+		d = a2 / (math.Pi * d * d)
+		pdf := d / 4
+		sample := 1 / (environmentSamples * pdf)
+		lod := math.Max(0.5*math.Log2(sample/texel)+1, 0)
+		u, v := equirectangularCoordinates(l)
+		sum = sum.Add(e.radiance.LevelSample(u, v, lod).MulScalar(nl))
+		weight += nl
+	}
+	return sum.DivScalar(weight)
+}
+
+// resampleEnvironment returns an equirectangular texture of the given size
+// with each texel computed by f from its direction.
+func resampleEnvironment(w, h int, f func(Vector) Color) *FloatTexture {
+	pixels := make([]Color, w*h)
+	parallel(runtime.NumCPU(), h, func(wi, y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < w; x++ {
+				u := float64(x) / float64(w-1)
+				v := 1 - float64(y)/float64(h-1)
+				c := f(equirectangularDirection(u, v))
+				pixels[y*w+x] = c.Alpha(1)
+			}
+		}
+	})
+	return NewFloatTexture(w, h, pixels)
+}
+
+// projectSH returns the spherical harmonic coefficients of an
+// equirectangular texture.
+func projectSH(t *FloatTexture) [9]Color {
+	var sh [9]Color
+	var total float64
+	for y := 0; y < t.Height; y++ {
+		v := 1 - (float64(y)+0.5)/float64(t.Height)
+		weight := math.Cos((v - 0.5) * math.Pi)
+		for x := 0; x < t.Width; x++ {
+			u := (float64(x) + 0.5) / float64(t.Width)
+			c := t.Pixels[y*t.Width+x]
+			b := shBasis(equirectangularDirection(u, v))
+			for i := range sh {
+				sh[i] = sh[i].Add(c.MulScalar(b[i] * weight))
+			}
+			total += weight
+		}
+	}
+	for i := range sh {
+		sh[i] = sh[i].MulScalar(4 * math.Pi / total)
+	}
+	return sh
+}
+
+// shBasis evaluates the first nine real spherical harmonics at unit
+// direction d.
+func shBasis(d Vector) [9]float64 {
+	x, y, z := d.X, d.Y, d.Z
+	return [9]float64{
+		0.282095,
+		0.488603 * y, 0.488603 * z, 0.488603 * x,
+		1.092548 * x * y, 1.092548 * y * z, 0.315392 * (3*z*z - 1),
+		1.092548 * x * z, 0.546274 * (x*x - y*y),
+	}
+}
+
+// hammersley returns the i-th of n points of the Hammersley sequence.
+func hammersley(i, n int) (float64, float64) {
+	b := uint32(i)
+	b = (b << 16) | (b >> 16)
+	b = ((b & 0x55555555) << 1) | ((b & 0xAAAAAAAA) >> 1)
+	b = ((b & 0x33333333) << 2) | ((b & 0xCCCCCCCC) >> 2)
+	b = ((b & 0x0F0F0F0F) << 4) | ((b & 0xF0F0F0F0) >> 4)
+	b = ((b & 0x00FF00FF) << 8) | ((b & 0xFF00FF00) >> 8)
+	return float64(i) / float64(n), float64(b) / (1 << 32)
+}
@@ -0,0 +1,51 @@
+package fauxgl
+
+import "math"
+
+// Screen space positions are snapped to a grid of 1/subPixels of a pixel
+// before rasterization, so that edge functions are exact.
+const (
+	subPixelBits = 8
+	subPixels    = 1 << subPixelBits
+)
+
+// fixed converts a screen space coordinate to subpixels.
+func fixed(x float64) int64 {
+	return int64(math.Floor(x*subPixels + 0.5))
+}
+
+// topLeftBias returns the bias for an edge function with the specified
+// increments along x and y. Samples exactly on an edge are covered only if
+// it is a left edge, or a horizontal edge at the top of the triangle.
+func topLeftBias(a, b int64) int64 {
+	if a > 0 || (a == 0 && b > 0) {
+		return 0
+	}
+	return -1
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+func ceilDiv(a, b int64) int64 {
+	return -floorDiv(-a, b)
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,70 @@
+package fauxgl
+
+import "math"
+
+// ToneMap maps a linear, unclamped color to the displayable [0, 1] range.
+type ToneMap func(Color) Color
+
+// EnableHDR switches the context to a floating point color buffer that
+// stores linear, unclamped colors. Call Resolve (or Image) to tone map the
+// result into the ColorBuffer.
+func (dc *Context) EnableHDR() {
+	if dc.SampleBuffer == nil {
+		dc.SampleBuffer = make([]Color, dc.Width*dc.Height*dc.Samples)
+		for i := range dc.SampleBuffer {
+			x, y := i/dc.Samples%dc.Width, i/dc.Samples/dc.Width
+			dc.SampleBuffer[i] = MakeColor(dc.ColorBuffer.NRGBAAt(x, y))
+		}
+	}
+	dc.hdr = true
+}
+
+func ToneMapReinhard(c Color) Color {
+	return Color{c.R / (1 + c.R), c.G / (1 + c.G), c.B / (1 + c.B), c.A}
+}
+
+// ToneMapACES uses Krzysztof Narkowicz's fit of the ACES filmic curve.
+func ToneMapACES(c Color) Color {
+	f := func(x float64) float64 {
+		const a, b, c, d, e = 2.51, 0.03, 2.43, 0.59, 0.14
+		return Clamp((x*(a*x+b))/(x*(c*x+d)+e), 0, 1)
+	}
+	return Color{f(c.R), f(c.G), f(c.B), c.A}
+}
+
+// ToneMapExposure scales colors by 2^exposure before applying toneMap, which
+// may be nil.
+func ToneMapExposure(exposure float64, toneMap ToneMap) ToneMap {
+	scale := math.Pow(2, exposure)
+	return func(c Color) Color {
+		c = c.MulScalar(scale).Alpha(c.A)
+		if toneMap != nil {
+			c = toneMap(c)
+		}
+		return c
+	}
+}
+
+// ToneMapGamma applies toneMap, which may be nil, and then gamma encodes the
+// result.
+func ToneMapGamma(gamma float64, toneMap ToneMap) ToneMap {
+	return func(c Color) Color {
+		if toneMap != nil {
+			c = toneMap(c)
+		}
+		a := c.A
+		c = c.Max(Transparent).Pow(1 / gamma)
+		return c.Alpha(a)
+	}
+}
+
+// ToneMapSRGB applies toneMap, which may be nil, and then sRGB encodes the
+// result.
+func ToneMapSRGB(toneMap ToneMap) ToneMap {
+	return func(c Color) Color {
+		if toneMap != nil {
+			c = toneMap(c)
+		}
+		return c.Max(Transparent).Alpha(c.A).SRGB()
+	}
+}
@@ -0,0 +1,170 @@
+package fauxgl
+
+import (
+	"context"
+	"fmt"
+)
+
+// IndexedMesh is a triangle mesh stored as a slice of unique vertices and a
+// slice of vertex indices, three per triangle. Drawing an IndexedMesh runs
+// the vertex shader once per vertex rather than once per triangle corner.
+type IndexedMesh struct {
+	Vertices []Vertex
+	Indices  []int
+}
+
+func NewIndexedMesh(vertices []Vertex, indices []int) *IndexedMesh {
+	return &IndexedMesh{vertices, indices}
+}
+
+// Indexed returns an IndexedMesh with the mesh's triangles, sharing
+// identical vertices. Lines and points are not included.
+func (m *Mesh) Indexed() *IndexedMesh {
+	type key struct {
+		position, normal, tangent, bitangent, texture Vector
+		color                                         Color
+		output                                        VectorW
+		attributes                                    string
+	}
+	lookup := make(map[key]int)
+	var vertices []Vertex
+	indices := make([]int, 0, len(m.Triangles)*3)
+	index := func(v Vertex) int {
+		// slices are not comparable, so user attributes are keyed by
+		// their formatted values
+		k := key{v.Position, v.Normal, v.Tangent, v.Bitangent, v.Texture, v.Color, v.Output, ""}
+		if v.Vectors != nil || v.Colors != nil || v.Floats != nil {
+			k.attributes = fmt.Sprint(v.Vectors, v.Colors, v.Floats)
+		}
+		i, ok := lookup[k]
+		if !ok {
+			i = len(vertices)
+			lookup[k] = i
+			vertices = append(vertices, v)
+		}
+		return i
+	}
+	for _, t := range m.Triangles {
+		indices = append(indices, index(t.V1), index(t.V2), index(t.V3))
+	}
+	return NewIndexedMesh(vertices, indices)
+}
+
+// Mesh returns a Mesh with the indexed mesh's triangles.
+func (m *IndexedMesh) Mesh() *Mesh {
+	triangles := make([]*Triangle, len(m.Indices)/3)
+	for i := range triangles {
+		v1 := m.Vertices[m.Indices[i*3+0]]
+		v2 := m.Vertices[m.Indices[i*3+1]]
+		v3 := m.Vertices[m.Indices[i*3+2]]
+		triangles[i] = NewTriangle(v1, v2, v3)
+	}
+	return NewTriangleMesh(triangles)
+}
+
+// Instance is a per-instance transformation and color for DrawInstanced.
+// Matrix is applied to vertex positions, normals and tangents before the vertex
+// shader is invoked. Color, if not Discard, replaces the vertex color.
+type Instance struct {
+	Matrix Matrix
+	Color  Color
+}
+
+func NewInstance(matrix Matrix, color Color) Instance {
+	return Instance{matrix, color}
+}
+
+func (instance *Instance) apply(v Vertex) Vertex {
+	v.Position = instance.Matrix.MulPosition(v.Position)
+	v.Normal = instance.Matrix.MulDirection(v.Normal)
+	v.Tangent, v.Bitangent = transformTangents(instance.Matrix, v.Tangent, v.Bitangent)
+	if instance.Color != Discard {
+		v.Color = instance.Color
+	}
+	return v
+}
+
+// DrawIndexed draws an indexed mesh, shading each vertex once.
+func (dc *Context) DrawIndexed(mesh *IndexedMesh) RasterizeInfo {
+	info, _ := dc.DrawIndexedContext(context.Background(), mesh)
+	return info
+}
+
+// DrawIndexedContext is like DrawIndexed but stops early if ctx is canceled,
+// returning ctx.Err().
+func (dc *Context) DrawIndexedContext(ctx context.Context, mesh *IndexedMesh) (RasterizeInfo, error) {
+	return dc.drawIndexed(ctx, mesh, nil)
+}
+
+// DrawInstanced draws an indexed mesh once for each instance. Primitives
+// are numbered consecutively across instances for picking, so the instance
+// of a picked primitive is its index divided by the number of triangles.
+func (dc *Context) DrawInstanced(mesh *IndexedMesh, instances []Instance) RasterizeInfo {
+	info, _ := dc.DrawInstancedContext(context.Background(), mesh, instances)
+	return info
+}
+
+// DrawInstancedContext is like DrawInstanced but stops early if ctx is
+// canceled, returning ctx.Err().
+func (dc *Context) DrawInstancedContext(ctx context.Context, mesh *IndexedMesh, instances []Instance) (RasterizeInfo, error) {
+	if len(instances) == 0 {
+		return RasterizeInfo{}, ctx.Err()
+	}
+	return dc.drawIndexed(ctx, mesh, instances)
+}
+
+func (dc *Context) drawIndexed(ctx context.Context, mesh *IndexedMesh, instances []Instance) (RasterizeInfo, error) {
+	nv := len(mesh.Vertices)
+	nt := len(mesh.Indices) / 3
+	if nt == 0 {
+		return RasterizeInfo{}, ctx.Err()
+	}
+	count := nt
+	if instances != nil {
+		count *= len(instances)
+	}
+
+	// shaded vertices of instances first through last, as used by the
+	// current batch; batches are in order so only the first instance may
+	// have been shaded by the previous batch, and an instance spanning
+	// several batches (or the mesh, when not instancing) is shaded once
+	var shaded, spare []Vertex
+	first, last := 0, -1
+	prepare := func(start, end int) {
+		i0 := start / nt
+		i1 := (end - 1) / nt
+		if i0 >= first && i1 <= last {
+			return
+		}
+		n := (i1 - i0 + 1) * nv
+		if cap(spare) < n {
+			spare = make([]Vertex, n)
+		}
+		spare = spare[:n]
+		k0 := 0
+		if i0 == last {
+			k0 = nv
+			copy(spare, shaded[(last-first)*nv:])
+		}
+		shaded, spare = spare, shaded
+		first, last = i0, i1
+		parallel(dc.workers(), n-k0, func(wi, j0, j1 int) {
+			for k := k0 + j0; k < k0+j1; k++ {
+				v := mesh.Vertices[k%nv]
+				if instances != nil {
+					v = instances[first+k/nv].apply(v)
+				}
+				shaded[k] = dc.Shader.Vertex(v)
+			}
+		})
+	}
+
+	return dc.drawBinned(ctx, count, prepare, func(i int, buf []rasterTriangle) []rasterTriangle {
+		vertices := shaded[(i/nt-first)*nv:]
+		j := i % nt * 3
+		v1 := vertices[mesh.Indices[j+0]]
+		v2 := vertices[mesh.Indices[j+1]]
+		v3 := vertices[mesh.Indices[j+2]]
+		return dc.setupShadedTriangle(v1, v2, v3, buf)
+	})
+}
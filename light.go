@@ -0,0 +1,150 @@
+package fauxgl
+
+import "math"
+
+// Light is a source of illumination for LitShader. Illuminate returns the
+// unit direction from a surface point toward the light and the color of the
+// light reaching it, including attenuation and shadowing. Ambient lights
+// return a zero direction and light surfaces regardless of their normal.
+type Light interface {
+	Illuminate(position, normal Vector) (Vector, Color)
+}
+
+// AmbientLight lights all surfaces equally.
+type AmbientLight struct {
+	Color Color
+}
+
+func NewAmbientLight(color Color) *AmbientLight {
+	return &AmbientLight{color}
+}
+
+func (l *AmbientLight) Illuminate(position, normal Vector) (Vector, Color) {
+	return Vector{}, l.Color
+}
+
+// HemisphereLight is an ambient light that blends from GroundColor for
+// surfaces facing away from Up to SkyColor for surfaces facing toward it.
+type HemisphereLight struct {
+	Up          Vector
+	SkyColor    Color
+	GroundColor Color
+}
+
+func NewHemisphereLight(up Vector, sky, ground Color) *HemisphereLight {
+	return &HemisphereLight{up.Normalize(), sky, ground}
+}
+
+func (l *HemisphereLight) Illuminate(position, normal Vector) (Vector, Color) {
+	t := (normal.Dot(l.Up) + 1) / 2
+	return Vector{}, l.GroundColor.Lerp(l.SkyColor, t)
+}
+
+// DirectionalLight is a light infinitely far away in Direction, as used by
+// PhongShader's LightDirection.
+type DirectionalLight struct {
+	Direction Vector
+	Color     Color
+	ShadowMap *ShadowMap
+}
+
+func NewDirectionalLight(direction Vector, color Color) *DirectionalLight {
+	return &DirectionalLight{direction.Normalize(), color, nil}
+}
+
+func (l *DirectionalLight) Illuminate(position, normal Vector) (Vector, Color) {
+	color := l.Color
+	if l.ShadowMap != nil && normal.Dot(l.Direction) > 0 {
+		color = color.MulScalar(l.ShadowMap.Visibility(position))
+	}
+	return l.Direction, color
+}
+
+// PointLight is a light at Position whose intensity falls off with distance
+// d as 1 / (Constant + Linear * d + Quadratic * d * d).
+type PointLight struct {
+	Position  Vector
+	Color     Color
+	Constant  float64
+	Linear    float64
+	Quadratic float64
+}
+
+func NewPointLight(position Vector, color Color) *PointLight {
+	return &PointLight{position, color, 1, 0, 0}
+}
+
+func (l *PointLight) Illuminate(position, normal Vector) (Vector, Color) {
+	d := l.Position.Sub(position)
+	distance := d.Length()
+	a := attenuation(distance, l.Constant, l.Linear, l.Quadratic)
+	return d.DivScalar(distance), l.Color.MulScalar(a)
+}
+
+// SpotLight is a point light at Position shining in Direction. Its
+// intensity falls off smoothly from InnerAngle to OuterAngle, in radians
+// from Direction, and with distance as for PointLight.
+type SpotLight struct {
+	Position   Vector
+	Direction  Vector
+	Color      Color
+	InnerAngle float64
+	OuterAngle float64
+	Constant   float64
+	Linear     float64
+	Quadratic  float64
+	ShadowMap  *ShadowMap
+}
+
+func NewSpotLight(position, direction Vector, color Color, innerAngle, outerAngle float64) *SpotLight {
+	return &SpotLight{
+		position, direction.Normalize(), color,
+		innerAngle, outerAngle, 1, 0, 0, nil}
+}
+
+func (l *SpotLight) Illuminate(position, normal Vector) (Vector, Color) {
+	d := l.Position.Sub(position)
+	distance := d.Length()
+	d = d.DivScalar(distance)
+	cos := -d.Dot(l.Direction)
+	t := smoothstep(math.Cos(l.OuterAngle), math.Cos(l.InnerAngle), cos)
+	a := t * attenuation(distance, l.Constant, l.Linear, l.Quadratic)
+	if a > 0 && l.ShadowMap != nil && normal.Dot(d) > 0 {
+		a *= l.ShadowMap.Visibility(position)
+	}
+	return d, l.Color.MulScalar(a)
+}
+
+func attenuation(d, constant, linear, quadratic float64) float64 {
+	return 1 / (constant + linear*d + quadratic*d*d)
+}
+
+func smoothstep(e0, e1, x float64) float64 {
+	if e0 == e1 {
+		if x < e0 {
+			return 0
+		}
+		return 1
+	}
+	t := Clamp((x-e0)/(e1-e0), 0, 1)
+	return t * t * (3 - 2*t)
+}
+
+// ThreePointLights returns a studio lighting setup for a subject at center
+// seen from eye: a key light above and to one side of the camera, a dimmer
+// fill light on the other side, a rim light behind the subject and a little
+// ambient light.
+func ThreePointLights(eye, center, up Vector) []Light {
+	forward := center.Sub(eye).Normalize()
+	right := forward.Cross(up).Normalize()
+	up = right.Cross(forward)
+	key := forward.Negate().Add(right.MulScalar(-0.8)).Add(up.MulScalar(0.8))
+	fill := forward.Negate().Add(right.MulScalar(1)).Add(up.MulScalar(0.2))
+	rim := forward.Add(up.MulScalar(0.8))
+	return []Light{
+		NewDirectionalLight(key, Gray(0.8)),
+		NewDirectionalLight(fill, Gray(0.35)),
+		NewDirectionalLight(rim, Gray(0.5)),
+		NewAmbientLight(Gray(0.1)),
+	}
+}
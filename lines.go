@@ -0,0 +1,295 @@
+package fauxgl
+
+import (
+	"context"
+	"math"
+)
+
+type LineCap int
+
+const (
+	_ LineCap = iota
+	LineCapButt
+	LineCapRound
+	LineCapSquare
+)
+
+type LineJoin int
+
+const (
+	_ LineJoin = iota
+	LineJoinMiter
+	LineJoinRound
+	LineJoinBevel
+)
+
+// lineCapJoin marks a polyline segment end that meets another segment at a
+// miter or bevel join; the join fills the gap so the end is not faded.
+const lineCapJoin LineCap = 0
+
+// lineSegment describes a styled line in screen space. distance is the
+// distance along the line (or polyline) at p0 and length is the length of
+// the whole line, used for dashing.
+type lineSegment struct {
+	p0, p1           Vector
+	halfWidth        float64
+	cap0, cap1       LineCap
+	smooth, dashed   bool
+	distance, length float64
+}
+
+// styledLines reports whether lines need more than the basic rasterization
+// of a square capped quad.
+func (dc *Context) styledLines() bool {
+	return dc.LineSmooth || dc.LineCap != LineCapSquare || len(dc.LineDash) > 0
+}
+
+// styledLine appends two triangles that cover the line, its caps and, if
+// LineSmooth is set, a one pixel border for anti-aliasing. Coverage within
+// the triangles is computed by lineCoverage.
+func (dc *Context) styledLine(v0, v1 Vertex, s0, s1 Vector, cap0, cap1 LineCap, distance, length float64, buf []rasterTriangle) []rasterTriangle {
+	hw := dc.LineWidth / 2
+	pad := 0.0
+	if dc.LineSmooth {
+		pad = 1
+	}
+	d := s1.Sub(s0)
+	l := math.Hypot(d.X, d.Y)
+	if l == 0 {
+		d = Vector{1, 0, 0}
+	} else {
+		d = d.DivScalar(l)
+	}
+	ext0 := hw + pad
+	if cap0 == LineCapButt || cap0 == lineCapJoin {
+		ext0 = pad
+	}
+	ext1 := hw + pad
+	if cap1 == LineCapButt || cap1 == lineCapJoin {
+		ext1 = pad
+	}
+	n := Vector{-d.Y, d.X, 0}.MulScalar(hw + pad)
+	e0 := s0.Sub(d.MulScalar(ext0))
+	e1 := s1.Add(d.MulScalar(ext1))
+	s00 := e0.Add(n)
+	s01 := e0.Sub(n)
+	s10 := e1.Add(n)
+	s11 := e1.Sub(n)
+	dashed := len(dc.LineDash) > 0
+	line := &lineSegment{s0, s1, hw, cap0, cap1, dc.LineSmooth, dashed, distance, length}
+	buf = append(buf, rasterTriangle{v1, v0, v0, s11, s01, s00, 0, line})
+	buf = append(buf, rasterTriangle{v1, v1, v0, s10, s11, s00, 0, line})
+	return buf
+}
+
+// lineCoverage returns the fraction of the pixel centered at x, y that is
+// covered by the line, taking caps and dashes into account.
+func (dc *Context) lineCoverage(line *lineSegment, x, y float64) float64 {
+	hw := line.halfWidth
+	d := line.p1.Sub(line.p0)
+	l := math.Hypot(d.X, d.Y)
+	var u, v float64
+	if l == 0 {
+		u, v = x-line.p0.X, math.Abs(y-line.p0.Y)
+	} else {
+		dx, dy := d.X/l, d.Y/l
+		qx, qy := x-line.p0.X, y-line.p0.Y
+		u = qx*dx + qy*dy
+		v = math.Abs(qx*dy - qy*dx)
+	}
+
+	// signed distance to the line, negative inside
+	var sd float64
+	if u <= 0 && line.cap0 == LineCapRound {
+		sd = math.Hypot(u, v) - hw
+	} else if u > l && line.cap1 == LineCapRound {
+		sd = math.Hypot(u-l, v) - hw
+	} else {
+		sd = math.Max(math.Max(-lineCapExtent(line.cap0, hw)-u, u-l-lineCapExtent(line.cap1, hw)), v-hw)
+	}
+	if line.dashed {
+		if u >= 0 && u <= l {
+			sd = math.Max(sd, dc.dashDistance(line.distance+u, line.length))
+		} else if dc.dashDistance(line.distance+Clamp(u, 0, l), line.length) > 0 {
+			// caps are drawn whole if the dash is on at the end point
+			return 0
+		}
+	}
+
+	if line.smooth {
+		return Clamp(0.5-sd, 0, 1)
+	}
+	if sd <= 0 {
+		return 1
+	}
+	return 0
+}
+
+// lineCapExtent returns how far past its end point a line extends.
+func lineCapExtent(cap LineCap, hw float64) float64 {
+	switch cap {
+	case LineCapSquare:
+		return hw
+	case lineCapJoin:
+		return math.Inf(1)
+	}
+	return 0
+}
+
+// dashDistance returns the signed distance from a position along a line to
+// the nearest "on" interval of the dash pattern, negative inside. Dashes are
+// not cut off at the ends of the line, which has the given length.
+func (dc *Context) dashDistance(position, length float64) float64 {
+	dash := dc.LineDash
+	if len(dash)%2 == 1 {
+		dash = append(dash[:len(dash):len(dash)], dash...)
+	}
+	var period float64
+	for _, x := range dash {
+		period += x
+	}
+	if period <= 0 {
+		return math.Inf(-1)
+	}
+	t := position + dc.LineDashOffset
+	t -= math.Floor(t/period) * period
+	var a float64
+	for i, x := range dash {
+		b := a + x
+		if t >= a && t < b {
+			if i%2 == 0 {
+				d0, d1 := t-a, b-t
+				if position-d0 <= 0 {
+					d0 = math.Inf(1)
+				}
+				if position+d1 >= length {
+					d1 = math.Inf(1)
+				}
+				return -math.Min(d0, d1)
+			}
+			// distance to the previous or next dash, wrapping around
+			return math.Min(t-a, b-t)
+		}
+		a = b
+	}
+	return 0
+}
+
+// DrawPolyline draws connected lines through the vertices, joined as
+// specified by LineJoin. Dash patterns continue from one segment to the
+// next.
+func (dc *Context) DrawPolyline(vertices []Vertex) RasterizeInfo {
+	info, _ := dc.DrawPolylineContext(context.Background(), vertices)
+	return info
+}
+
+// DrawPolylineContext is like DrawPolyline but stops early if ctx is
+// canceled, returning ctx.Err().
+func (dc *Context) DrawPolylineContext(ctx context.Context, vertices []Vertex) (RasterizeInfo, error) {
+	if len(vertices) < 2 {
+		return RasterizeInfo{}, ctx.Err()
+	}
+	shaded := make([]Vertex, len(vertices))
+	screen := make([]Vector, len(vertices))
+	distances := make([]float64, len(vertices))
+	for i, v := range vertices {
+		v = dc.Shader.Vertex(v)
+		shaded[i] = v
+		screen[i] = dc.project(v)
+		if i > 0 {
+			distances[i] = distances[i-1]
+			if shaded[i-1].Output.W > 0 && v.Output.W > 0 {
+				d := screen[i].Sub(screen[i-1])
+				distances[i] += math.Hypot(d.X, d.Y)
+			}
+		}
+	}
+	return dc.drawBinned(ctx, len(vertices)-1, nil, func(i int, buf []rasterTriangle) []rasterTriangle {
+		return dc.setupPolylineSegment(shaded, screen, distances, i, buf)
+	})
+}
+
+func (dc *Context) setupPolylineSegment(shaded []Vertex, screen []Vector, distances []float64, i int, buf []rasterTriangle) []rasterTriangle {
+	n := len(shaded)
+	v0, v1 := shaded[i], shaded[i+1]
+	cap0, cap1 := dc.LineCap, dc.LineCap
+	join := lineCapJoin
+	if dc.LineJoin == LineJoinRound {
+		join = LineCapRound
+	}
+	if i > 0 {
+		cap0 = join
+	}
+	if i+2 < n {
+		cap1 = join
+	}
+	distance := distances[i]
+	if dc.outside(v0) || dc.outside(v1) {
+		// clip to viewing volume
+		line := clipLine(NewLine(v0, v1), dc.clipPlanes)
+		if line == nil {
+			return buf
+		}
+		if v0.Output.W > 0 {
+			d := dc.project(line.V1).Sub(screen[i])
+			distance += math.Hypot(d.X, d.Y)
+		}
+		v0, v1 = line.V1, line.V2
+	}
+	s0 := dc.project(v0)
+	s1 := dc.project(v1)
+	buf = dc.styledLine(v0, v1, s0, s1, cap0, cap1, distance, distances[n-1], buf)
+
+	// fill the outside of the join with the next segment
+	if i+2 < n && join == lineCapJoin && !dc.outside(shaded[i+1]) {
+		if len(dc.LineDash) > 0 && dc.dashDistance(distances[i+1], distances[n-1]) > 0 {
+			return buf
+		}
+		buf = dc.lineJoin(shaded[i+1], screen[i], screen[i+1], screen[i+2], buf)
+	}
+	return buf
+}
+
+// lineJoin appends triangles for a bevel or miter join at p, between the
+// segments from a to p and from p to b.
+func (dc *Context) lineJoin(v Vertex, a, p, b Vector, buf []rasterTriangle) []rasterTriangle {
+	hw := dc.LineWidth / 2
+	da := p.Sub(a)
+	db := b.Sub(p)
+	la := math.Hypot(da.X, da.Y)
+	lb := math.Hypot(db.X, db.Y)
+	if la == 0 || lb == 0 {
+		return buf
+	}
+	cross := da.X*db.Y - da.Y*db.X
+	if math.Abs(cross) < 1e-9*la*lb {
+		return buf
+	}
+	sign := 1.0
+	if cross > 0 {
+		sign = -1
+	}
+	na := Vector{-da.Y / la, da.X / la, 0}.MulScalar(hw * sign)
+	nb := Vector{-db.Y / lb, db.X / lb, 0}.MulScalar(hw * sign)
+	c1 := p.Add(na)
+	c2 := p.Add(nb)
+	if dc.LineJoin == LineJoinMiter {
+		m := na.Add(nb)
+		cos := m.Length() / (2 * hw)
+		if cos > 0 && 1/cos <= dc.LineMiterLimit {
+			// miter point at hw / cos(half angle) from p
+			m = p.Add(m.Normalize().MulScalar(hw / cos))
+			buf = joinTriangle(v, p, c1, m, buf)
+			return joinTriangle(v, p, m, c2, buf)
+		}
+	}
+	return joinTriangle(v, p, c1, c2, buf)
+}
+
+// joinTriangle appends a flat triangle, wound the way rasterize expects.
+func joinTriangle(v Vertex, s0, s1, s2 Vector, buf []rasterTriangle) []rasterTriangle {
+	if edge(s0, s1, s2) < 0 {
+		s1, s2 = s2, s1
+	}
+	return append(buf, rasterTriangle{v, v, v, s0, s1, s2, 0, nil})
+}
@@ -78,6 +78,21 @@ func Frustum(l, r, b, t, n, f float64) Matrix {
 		0, 0, -1, 0}
 }
 
+// FrustumReverseZ is like Frustum but maps the near plane to a depth of 1 and
+// the far plane to a depth of 0. Use it with a DepthFunc of
+// CompareGreaterEqual and a ClearDepth of 0.
+func FrustumReverseZ(l, r, b, t, n, f float64) Matrix {
+	t1 := 2 * n
+	t2 := r - l
+	t3 := t - b
+	t4 := f - n
+	return Matrix{
+		t1 / t2, 0, (r + l) / t2, 0,
+		0, t1 / t3, (t + b) / t3, 0,
+		0, 0, (f + n) / t4, (t1 * f) / t4,
+		0, 0, -1, 0}
+}
+
 func Orthographic(l, r, b, t, n, f float64) Matrix {
 	return Matrix{
 		2 / (r - l), 0, 0, -(r + l) / (r - l),
@@ -92,6 +107,12 @@ func Perspective(fovy, aspect, near, far float64) Matrix {
 	return Frustum(-xmax, xmax, -ymax, ymax, near, far)
 }
 
+func PerspectiveReverseZ(fovy, aspect, near, far float64) Matrix {
+	ymax := near * math.Tan(fovy*math.Pi/360)
+	xmax := ymax * aspect
+	return FrustumReverseZ(-xmax, xmax, -ymax, ymax, near, far)
+}
+
 func LookAt(eye, center, up Vector) Matrix {
 	z := eye.Sub(center).Normalize()
 	x := up.Cross(z).Normalize()
@@ -160,6 +181,10 @@ func (m Matrix) Frustum(l, r, b, t, n, f float64) Matrix {
 	return Frustum(l, r, b, t, n, f).Mul(m)
 }
 
+func (m Matrix) FrustumReverseZ(l, r, b, t, n, f float64) Matrix {
+	return FrustumReverseZ(l, r, b, t, n, f).Mul(m)
+}
+
 func (m Matrix) Orthographic(l, r, b, t, n, f float64) Matrix {
 	return Orthographic(l, r, b, t, n, f).Mul(m)
 }
@@ -168,6 +193,10 @@ func (m Matrix) Perspective(fovy, aspect, near, far float64) Matrix {
 	return Perspective(fovy, aspect, near, far).Mul(m)
 }
 
+func (m Matrix) PerspectiveReverseZ(fovy, aspect, near, far float64) Matrix {
+	return PerspectiveReverseZ(fovy, aspect, near, far).Mul(m)
+}
+
 func (m Matrix) LookAt(eye, center, up Vector) Matrix {
 	return LookAt(eye, center, up).Mul(m)
 }
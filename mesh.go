@@ -9,6 +9,7 @@ import (
 type Mesh struct {
 	Triangles []*Triangle
 	Lines     []*Line
+	Points    []*Point
 	box       *Box
 }
 
@@ -17,15 +18,19 @@ func NewEmptyMesh() *Mesh {
 }
 
 func NewMesh(triangles []*Triangle, lines []*Line) *Mesh {
-	return &Mesh{triangles, lines, nil}
+	return &Mesh{triangles, lines, nil, nil}
 }
 
 func NewTriangleMesh(triangles []*Triangle) *Mesh {
-	return &Mesh{triangles, nil, nil}
+	return &Mesh{triangles, nil, nil, nil}
 }
 
 func NewLineMesh(lines []*Line) *Mesh {
-	return &Mesh{nil, lines, nil}
+	return &Mesh{nil, lines, nil, nil}
+}
+
+func NewPointMesh(points []*Point) *Mesh {
+	return &Mesh{nil, nil, points, nil}
 }
 
 func (m *Mesh) dirty() {
@@ -35,6 +40,7 @@ func (m *Mesh) dirty() {
 func (m *Mesh) Copy() *Mesh {
 	triangles := make([]*Triangle, len(m.Triangles))
 	lines := make([]*Line, len(m.Lines))
+	points := make([]*Point, len(m.Points))
 	for i, t := range m.Triangles {
 		a := *t
 		triangles[i] = &a
@@ -43,12 +49,17 @@ func (m *Mesh) Copy() *Mesh {
 		a := *l
 		lines[i] = &a
 	}
-	return NewMesh(triangles, lines)
+	for i, p := range m.Points {
+		a := *p
+		points[i] = &a
+	}
+	return &Mesh{triangles, lines, points, nil}
 }
 
 func (a *Mesh) Add(b *Mesh) {
 	a.Triangles = append(a.Triangles, b.Triangles...)
 	a.Lines = append(a.Lines, b.Lines...)
+	a.Points = append(a.Points, b.Points...)
 	a.dirty()
 }
 
@@ -159,6 +170,9 @@ func (m *Mesh) BoundingBox() Box {
 		for _, l := range m.Lines {
 			box = box.Extend(l.BoundingBox())
 		}
+		for _, p := range m.Points {
+			box = box.Extend(p.BoundingBox())
+		}
 		m.box = &box
 	}
 	return *m.box
@@ -171,6 +185,9 @@ func (m *Mesh) Transform(matrix Matrix) {
 	for _, l := range m.Lines {
 		l.Transform(matrix)
 	}
+	for _, p := range m.Points {
+		p.Transform(matrix)
+	}
 	m.dirty()
 }
 
@@ -0,0 +1,72 @@
+package fauxgl
+
+import "fmt"
+
+const maxSamples = 16
+
+// standard sample positions in sixteenths of a pixel, relative to the center
+var samplePatterns = map[int][][2]float64{
+	1: {{0, 0}},
+	2: {{4, 4}, {-4, -4}},
+	4: {{-2, -6}, {6, -2}, {-6, 2}, {2, 6}},
+	8: {
+		{1, -3}, {-1, 3}, {5, 1}, {-3, -5},
+		{-5, 5}, {-7, -1}, {3, 7}, {7, -7}},
+	16: {
+		{1, 1}, {-1, -3}, {-3, 2}, {4, -1},
+		{-5, -2}, {2, 5}, {5, 3}, {3, -5},
+		{-2, 6}, {0, -7}, {-4, -6}, {-6, 4},
+		{-8, 0}, {7, -4}, {6, 7}, {-7, -8}},
+}
+
+func samplePattern(samples int) []Vector {
+	pattern, ok := samplePatterns[samples]
+	if !ok {
+		panic(fmt.Sprintf("fauxgl: unsupported sample count: %d", samples))
+	}
+	result := make([]Vector, len(pattern))
+	for i, p := range pattern {
+		result[i] = Vector{p[0] / 16, p[1] / 16, 0}
+	}
+	return result
+}
+
+// ResolveColors averages the samples of each pixel and returns the resulting
+// colors in row-major order. Colors are not clamped if HDR is enabled.
+func (dc *Context) ResolveColors() []Color {
+	n := dc.Samples
+	result := make([]Color, dc.Width*dc.Height)
+	if dc.SampleBuffer == nil {
+		for i := range result {
+			result[i] = MakeColor(dc.ColorBuffer.NRGBAAt(i%dc.Width, i/dc.Width))
+		}
+		return result
+	}
+	for i := range result {
+		var c Color
+		for _, s := range dc.SampleBuffer[i*n : i*n+n] {
+			a := Clamp(s.A, 0, 1)
+			c = c.Add(s.MulScalar(a).Alpha(a))
+		}
+		if c.A > 0 {
+			c = c.DivScalar(c.A).Alpha(c.A / float64(n))
+		}
+		result[i] = c
+	}
+	return result
+}
+
+// Resolve averages the samples of a multisample or HDR context into the
+// ColorBuffer, applying the context's ToneMap if set. It does nothing if the
+// context renders directly into the ColorBuffer.
+func (dc *Context) Resolve() {
+	if dc.SampleBuffer == nil {
+		return
+	}
+	for i, c := range dc.ResolveColors() {
+		if dc.ToneMap != nil {
+			c = dc.ToneMap(c).Alpha(c.A)
+		}
+		dc.ColorBuffer.SetNRGBA(i%dc.Width, i/dc.Width, c.NRGBA())
+	}
+}
@@ -0,0 +1,169 @@
+package fauxgl
+
+import "math"
+
+// ToonShader implements cel shading: diffuse lighting is quantized into
+// Bands flat steps and specular highlights are either fully on or off.
+// Draw Mesh.Silhouette and Mesh.SharpEdges lines over the result, with a
+// negative DepthBias, for outlines.
+type ToonShader struct {
+	Matrix         Matrix
+	LightDirection Vector
+	CameraPosition Vector
+	ObjectColor    Color
+	AmbientColor   Color
+	DiffuseColor   Color
+	SpecularColor  Color
+	Texture        Texture
+	Bands          int
+	SpecularPower  float64
+}
+
+func NewToonShader(matrix Matrix, lightDirection, cameraPosition Vector) *ToonShader {
+	ambient := Color{0.3, 0.3, 0.3, 1}
+	diffuse := Color{0.7, 0.7, 0.7, 1}
+	specular := Color{0.3, 0.3, 0.3, 1}
+	return &ToonShader{
+		matrix, lightDirection, cameraPosition,
+		Discard, ambient, diffuse, specular, nil, 3, 32}
+}
+
+func (shader *ToonShader) Vertex(v Vertex) Vertex {
+	v.Output = shader.Matrix.MulPositionW(v.Position)
+	return v
+}
+
+func (shader *ToonShader) Fragment(v Vertex) Color {
+	color := v.Color
+	if shader.ObjectColor != Discard {
+		color = shader.ObjectColor
+	}
+	if shader.Texture != nil {
+		color = shader.Texture.BilinearSample(v.Texture.X, v.Texture.Y)
+	}
+	diffuse := math.Max(v.Normal.Dot(shader.LightDirection), 0)
+	if shader.Bands > 1 {
+		n := float64(shader.Bands)
+		diffuse = math.Min(math.Floor(diffuse*n)/(n-1), 1)
+	} else if diffuse > 0 {
+		diffuse = 1
+	}
+	light := shader.AmbientColor.Add(shader.DiffuseColor.MulScalar(diffuse))
+	result := color.Mul(light)
+	if diffuse > 0 && shader.SpecularPower > 0 {
+		camera := shader.CameraPosition.Sub(v.Position).Normalize()
+		half := shader.LightDirection.Add(camera).Normalize()
+		if math.Pow(math.Max(v.Normal.Dot(half), 0), shader.SpecularPower) > 0.5 {
+			result = result.Add(shader.SpecularColor)
+		}
+	}
+	return result.Alpha(color.A)
+}
+
+// GoochShader implements Gooch et al.'s technical illustration shading,
+// which conveys shape by blending from a cool color on surfaces facing away
+// from the light to a warm color on surfaces facing it, keeping shadows
+// distinct from black outlines.
+type GoochShader struct {
+	Matrix         Matrix
+	LightDirection Vector
+	CameraPosition Vector
+	ObjectColor    Color
+	CoolColor      Color
+	WarmColor      Color
+	Alpha          float64
+	Beta           float64
+	SpecularColor  Color
+	SpecularPower  float64
+}
+
+func NewGoochShader(matrix Matrix, lightDirection, cameraPosition Vector) *GoochShader {
+	cool := Color{0, 0, 0.55, 1}
+	warm := Color{0.3, 0.3, 0, 1}
+	specular := Color{1, 1, 1, 1}
+	return &GoochShader{
+		matrix, lightDirection, cameraPosition,
+		Discard, cool, warm, 0.25, 0.5, specular, 32}
+}
+
+func (shader *GoochShader) Vertex(v Vertex) Vertex {
+	v.Output = shader.Matrix.MulPositionW(v.Position)
+	return v
+}
+
+func (shader *GoochShader) Fragment(v Vertex) Color {
+	color := v.Color
+	if shader.ObjectColor != Discard {
+		color = shader.ObjectColor
+	}
+	cool := shader.CoolColor.Add(color.MulScalar(shader.Alpha))
+	warm := shader.WarmColor.Add(color.MulScalar(shader.Beta))
+	t := (1 + v.Normal.Dot(shader.LightDirection)) / 2
+	result := cool.Lerp(warm, t)
+	if shader.SpecularPower > 0 {
+		camera := shader.CameraPosition.Sub(v.Position).Normalize()
+		reflected := shader.LightDirection.Negate().Reflect(v.Normal)
+		specular := math.Max(camera.Dot(reflected), 0)
+		if specular > 0 {
+			specular = math.Pow(specular, shader.SpecularPower)
+			result = result.Lerp(shader.SpecularColor, specular)
+		}
+	}
+	return result.Min(White).Alpha(color.A)
+}
+
+// HatchingShader draws diffuse lighting as layers of screen space
+// cross-hatched ink lines: the darker the surface, the more layers are drawn
+// and the thicker their lines. Width and Height are the size of the
+// context, as the lines are laid out in pixels.
+type HatchingShader struct {
+	Matrix         Matrix
+	LightDirection Vector
+	InkColor       Color
+	PaperColor     Color
+	Width          int
+	Height         int
+	Spacing        float64
+	LineWidth      float64
+	Ambient        float64
+}
+
+func NewHatchingShader(matrix Matrix, lightDirection Vector, width, height int) *HatchingShader {
+	return &HatchingShader{
+		matrix, lightDirection, Black, White,
+		width, height, 8, 2, 0.1}
+}
+
+func (shader *HatchingShader) Vertex(v Vertex) Vertex {
+	v.Output = shader.Matrix.MulPositionW(v.Position)
+	return v
+}
+
+// hatchAngles are the directions of successive hatching layers.
+var hatchAngles = [...]float64{Radians(45), Radians(-45), Radians(0), Radians(90)}
+
+func (shader *HatchingShader) Fragment(v Vertex) Color {
+	diffuse := math.Max(v.Normal.Dot(shader.LightDirection), 0)
+	tone := 1 - Clamp(shader.Ambient+(1-shader.Ambient)*diffuse, 0, 1)
+	x := (v.Output.X/v.Output.W + 1) / 2 * float64(shader.Width)
+	y := (v.Output.Y/v.Output.W + 1) / 2 * float64(shader.Height)
+	n := float64(len(hatchAngles))
+	var ink float64
+	for i, angle := range hatchAngles {
+		// each layer fades in over its share of the tonal range
+		t := Clamp((tone-float64(i)/n)*n, 0, 1)
+		if t == 0 {
+			break
+		}
+		s, c := math.Sincos(angle)
+		d := x*c + y*s
+		if i >= 2 {
+			// offset later layers so they don't overlap earlier ones
+			d += shader.Spacing / 2
+		}
+		d = math.Abs(d - shader.Spacing*math.Floor(d/shader.Spacing+0.5))
+		coverage := Clamp(shader.LineWidth*t/2-d+0.5, 0, 1)
+		ink = math.Max(ink, coverage)
+	}
+	return shader.PaperColor.Lerp(shader.InkColor, ink)
+}
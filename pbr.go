@@ -0,0 +1,179 @@
+package fauxgl
+
+import "math"
+
+// PBRShader implements the glTF metallic-roughness material model: a
+// Cook-Torrance specular BRDF with the GGX distribution and a Lambertian
+// diffuse term, lit by Lights and, for image based lighting, Environment.
+//
+// Each texture is optional and multiplies its factor. BaseColorTexture and
+// EmissiveTexture are sRGB encoded, MetallicRoughnessTexture stores
+// roughness in green and metallic in blue, and OcclusionTexture stores
+// ambient occlusion in red. NormalMap is a tangent space normal map and
+// requires vertex tangents, see Mesh.ComputeTangents. If BaseColor is
+// Discard, the vertex color is used instead. Occlusion only darkens ambient
+// lights and the environment.
+//
+// The shader outputs linear radiance: render with EnableHDR and ToneMapSRGB
+// to match other glTF viewers. As the diffuse term is divided by pi, a
+// directional light of intensity pi lights a white surface facing it to 1.
+type PBRShader struct {
+	Matrix                   Matrix
+	CameraPosition           Vector
+	Lights                   []Light
+	BaseColor                Color
+	Metallic                 float64
+	Roughness                float64
+	Occlusion                float64
+	Emissive                 Color
+	BaseColorTexture         Texture
+	MetallicRoughnessTexture Texture
+	OcclusionTexture         Texture
+	EmissiveTexture          Texture
+	NormalMap                Texture
+	Environment              *Environment
+}
+
+func NewPBRShader(matrix Matrix, cameraPosition Vector, lights ...Light) *PBRShader {
+	return &PBRShader{
+		matrix, cameraPosition, lights,
+		White, 1, 1, 1, Black, nil, nil, nil, nil, nil, nil}
+}
+
+func (shader *PBRShader) Vertex(v Vertex) Vertex {
+	v.Output = shader.Matrix.MulPositionW(v.Position)
+	return v
+}
+
+func (shader *PBRShader) Fragment(v Vertex) Color {
+	return shader.shade(v, nil, nil)
+}
+
+func (shader *PBRShader) UsesDerivatives() bool {
+	return usesGradients(
+		shader.BaseColorTexture, shader.MetallicRoughnessTexture,
+		shader.OcclusionTexture, shader.EmissiveTexture, shader.NormalMap)
+}
+
+func (shader *PBRShader) FragmentDerivatives(v, dx, dy Vertex) Color {
+	return shader.shade(v, &dx, &dy)
+}
+
+func (shader *PBRShader) shade(v Vertex, dx, dy *Vertex) Color {
+	base := shader.BaseColor
+	if base == Discard {
+		base = v.Color
+	}
+	if shader.BaseColorTexture != nil {
+		base = base.Mul(sampleTexture(shader.BaseColorTexture, v, dx, dy).Linear())
+	}
+	metallic := shader.Metallic
+	roughness := shader.Roughness
+	if shader.MetallicRoughnessTexture != nil {
+		c := sampleTexture(shader.MetallicRoughnessTexture, v, dx, dy)
+		roughness *= c.G
+		metallic *= c.B
+	}
+	occlusion := shader.Occlusion
+	if shader.OcclusionTexture != nil {
+		occlusion *= sampleTexture(shader.OcclusionTexture, v, dx, dy).R
+	}
+	emissive := shader.Emissive
+	if shader.EmissiveTexture != nil {
+		emissive = emissive.Mul(sampleTexture(shader.EmissiveTexture, v, dx, dy).Linear())
+	}
+	m := newPBRMaterial(base, metallic, roughness)
+	n := v.Normal
+	if shader.NormalMap != nil {
+		n = TangentSpaceNormal(v, sampleTexture(shader.NormalMap, v, dx, dy))
+	}
+	view := shader.CameraPosition.Sub(v.Position).Normalize()
+	color := emissive.Alpha(0)
+	for _, l := range shader.Lights {
+		direction, intensity := l.Illuminate(v.Position, n)
+		if direction == (Vector{}) {
+			ambient := m.ambient(n, view).Mul(intensity)
+			color = color.Add(ambient.MulScalar(occlusion))
+		} else {
+			color = color.Add(m.brdf(n, view, direction).Mul(intensity))
+		}
+	}
+	if e := shader.Environment; e != nil {
+		r := view.Negate().Reflect(n)
+		irradiance := e.Irradiance(n)
+		radiance := e.Specular(r, math.Sqrt(m.alpha))
+		indirect := m.indirect(n, view, irradiance, radiance)
+		color = color.Add(indirect.MulScalar(occlusion))
+	}
+	return color.Alpha(base.A)
+}
+
+// pbrMaterial holds the inputs of the metallic-roughness BRDF.
+type pbrMaterial struct {
+	diffuse Color
+	f0      Color
+	alpha   float64
+}
+
+func newPBRMaterial(base Color, metallic, roughness float64) pbrMaterial {
+	metallic = Clamp(metallic, 0, 1)
+	roughness = Clamp(roughness, 0.03, 1)
+	diffuse := base.MulScalar(1 - metallic)
+	f0 := Gray(0.04).Lerp(base, metallic)
+	return pbrMaterial{diffuse, f0, roughness * roughness}
+}
+
+// brdf returns the light reflected toward view from a unit light arriving
+// from direction, including the cosine term.
+func (m pbrMaterial) brdf(n, view, direction Vector) Color {
+	nl := n.Dot(direction)
+	if nl <= 0 {
+		return Color{}
+	}
+	nv := math.Max(n.Dot(view), 1e-4)
+	h := direction.Add(view).Normalize()
+	nh := math.Max(n.Dot(h), 0)
+	vh := math.Max(view.Dot(h), 0)
+	a2 := m.alpha * m.alpha
+	// GGX normal distribution
+	d := nh*nh*(a2-1) + 1
+	d = a2 / (math.Pi * d * d)
+	// height-correlated Smith visibility, including 1 / (4 nl nv)
+	gv := nl * math.Sqrt(nv*nv*(1-a2)+a2)
+	gl := nv * math.Sqrt(nl*nl*(1-a2)+a2)
+	vis := 0.5 / (gv + gl)
+	// Schlick Fresnel
+	f := m.f0.Add(White.Sub(m.f0).MulScalar(math.Pow(1-vh, 5)))
+	specular := f.MulScalar(d * vis)
+	diffuse := White.Sub(f).Mul(m.diffuse).MulScalar(1 / math.Pi)
+	return diffuse.Add(specular).MulScalar(nl)
+}
+
+// ambient returns the light reflected toward view from uniform unit light
+// arriving from all directions.
+func (m pbrMaterial) ambient(n, view Vector) Color {
+	return m.indirect(n, view, White, White)
+}
+
+// indirect returns the light reflected toward view from an environment with
+// the given irradiance and prefiltered specular radiance, using Karis'
+// analytic approximation of the split sum environment BRDF.
+func (m pbrMaterial) indirect(n, view Vector, irradiance, radiance Color) Color {
+	nv := math.Max(n.Dot(view), 0)
+	a, b := environmentBRDF(math.Sqrt(m.alpha), nv)
+	specular := m.f0.MulScalar(a).AddScalar(b)
+	return m.diffuse.Mul(irradiance).Add(specular.Mul(radiance))
+}
+
+// environmentBRDF returns the scale and bias applied to f0 by the
+// integrated specular BRDF at the given roughness and n dot v.
+func environmentBRDF(roughness, nv float64) (float64, float64) {
+	c0 := [4]float64{-1, -0.0275, -0.572, 0.022}
+	c1 := [4]float64{1, 0.0425, 1.04, -0.04}
+	r := [4]float64{}
+	for i := range r {
+		r[i] = roughness*c0[i] + c1[i]
+	}
+	a004 := math.Min(r[0]*r[0], math.Exp2(-9.28*nv))*r[0] + r[1]
+	return a004*-1.04 + r[2], a004*1.04 + r[3]
+}
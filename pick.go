@@ -0,0 +1,73 @@
+package fauxgl
+
+// PixelID identifies the primitive that was drawn at a pixel. Mesh is the
+// value of Context.MeshID at the time of drawing and Primitive is the index
+// of the triangle, line or point within the draw call. DrawMesh numbers its
+// lines after its triangles and its points after its lines.
+type PixelID struct {
+	Mesh      int32
+	Primitive int32
+}
+
+var NoPixelID = PixelID{-1, -1}
+
+// PickResult is returned by Context.Pick.
+type PickResult struct {
+	PixelID
+	Depth    float64
+	Position Vector
+}
+
+// EnableIDBuffer allocates an ID buffer that records which mesh and
+// primitive was drawn at each pixel. Like the color buffer, it is only
+// written when WriteColor is set.
+func (dc *Context) EnableIDBuffer() {
+	if dc.IDBuffer == nil {
+		dc.IDBuffer = make([]PixelID, dc.Width*dc.Height)
+		dc.ClearIDBuffer()
+	}
+}
+
+func (dc *Context) ClearIDBuffer() {
+	for i := range dc.IDBuffer {
+		dc.IDBuffer[i] = NoPixelID
+	}
+}
+
+// Pick returns what was drawn at the specified pixel. The world position is
+// reconstructed from the depth buffer using the inverse of matrix, which
+// should be the matrix the shader used to draw the pixel, at the nearest of
+// the pixel's covered samples. ok is false if the ID buffer is not enabled or
+// nothing was drawn at the pixel.
+func (dc *Context) Pick(x, y int, matrix Matrix) (result PickResult, ok bool) {
+	if dc.IDBuffer == nil || x < 0 || y < 0 || x >= dc.Width || y >= dc.Height {
+		return
+	}
+	i := y*dc.Width + x
+	id := dc.IDBuffer[i]
+	if id == NoPixelID {
+		return
+	}
+	best := -1
+	for s := 0; s < dc.Samples; s++ {
+		z := dc.DepthBuffer[i*dc.Samples+s]
+		if z == dc.ClearDepth {
+			continue
+		}
+		if best < 0 || dc.DepthFunc.compare(z, dc.DepthBuffer[i*dc.Samples+best]) {
+			best = s
+		}
+	}
+	if best < 0 {
+		return
+	}
+	z := dc.DepthBuffer[i*dc.Samples+best]
+	o := dc.samples[best]
+	s := Vector{float64(x) + 0.5 + o.X, float64(y) + 0.5 + o.Y, z}
+	ndc := dc.screenMatrix.Inverse().MulPosition(s)
+	p := matrix.Inverse().MulPositionW(ndc)
+	result.PixelID = id
+	result.Depth = z
+	result.Position = p.DivScalar(p.W).Vector()
+	return result, true
+}
@@ -178,7 +178,7 @@ func loadPlyAscii(file *os.File, elements []plyElement) (*Mesh, error) {
 			}
 		}
 	}
-	return NewTriangleMesh(triangles), nil
+	return plyMesh(vertexes, triangles), nil
 }
 
 func loadPlyBinary(file *os.File, elements []plyElement, order binary.ByteOrder) (*Mesh, error) {
@@ -232,7 +232,19 @@ func loadPlyBinary(file *os.File, elements []plyElement, order binary.ByteOrder)
 			}
 		}
 	}
-	return NewTriangleMesh(triangles), nil
+	return plyMesh(vertexes, triangles), nil
+}
+
+// plyMesh returns a triangle mesh, or a point cloud if there are no faces.
+func plyMesh(vertexes []Vector, triangles []*Triangle) *Mesh {
+	if len(triangles) == 0 {
+		points := make([]*Point, len(vertexes))
+		for i, v := range vertexes {
+			points[i] = NewPointForPosition(v)
+		}
+		return NewPointMesh(points)
+	}
+	return NewTriangleMesh(triangles)
 }
 
 func readPlyInt(file *os.File, order binary.ByteOrder, dataType plyDataType) (int, error) {
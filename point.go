@@ -0,0 +1,22 @@
+package fauxgl
+
+type Point struct {
+	V Vertex
+}
+
+func NewPoint(v Vertex) *Point {
+	return &Point{v}
+}
+
+func NewPointForPosition(p Vector) *Point {
+	return NewPoint(Vertex{Position: p})
+}
+
+func (p *Point) BoundingBox() Box {
+	return Box{p.V.Position, p.V.Position}
+}
+
+func (p *Point) Transform(matrix Matrix) {
+	p.V.Position = matrix.MulPosition(p.V.Position)
+	p.V.Normal = matrix.MulDirection(p.V.Normal)
+}
@@ -0,0 +1,109 @@
+package fauxgl
+
+import (
+	"context"
+	"math"
+)
+
+type PointShape int
+
+const (
+	_ PointShape = iota
+	PointShapeCircle
+	PointShapeSquare
+)
+
+// setupPoint runs the vertex shader on a point and appends a screen aligned
+// quad covering it to buf. Points are discarded if their center is outside
+// the near, far or user clip planes.
+func (dc *Context) setupPoint(p *Point, buf []rasterTriangle) []rasterTriangle {
+	// invoke vertex shader
+	v := dc.Shader.Vertex(p.V)
+	if v.Output.W <= 0 {
+		return buf
+	}
+	for _, plane := range dc.clipPlanes[4:] {
+		if v.Output.Sub(plane.P).Dot(plane.N) < 0 {
+			return buf
+		}
+	}
+	s := dc.project(v)
+
+	// size in pixels
+	r := dc.PointSize / 2
+	if dc.PointWorldSize {
+		r = dc.projectedRadius(p.V, s, r)
+	}
+	if r <= 0 {
+		return buf
+	}
+
+	cap := LineCapRound
+	if dc.PointShape == PointShapeSquare {
+		cap = LineCapSquare
+	}
+	point := &lineSegment{s, s, r, cap, cap, dc.PointSmooth, false, 0, 0}
+
+	// corners, padded by a pixel for anti-aliasing
+	e := r
+	if dc.PointSmooth {
+		e++
+	}
+	s00 := Vector{s.X - e, s.Y - e, s.Z}
+	s01 := Vector{s.X - e, s.Y + e, s.Z}
+	s10 := Vector{s.X + e, s.Y - e, s.Z}
+	s11 := Vector{s.X + e, s.Y + e, s.Z}
+	v00, v01, v10, v11 := v, v, v, v
+	if dc.PointSprite {
+		// texture coordinates span the point from 0 to 1, with v up
+		t0 := 0.5 - e/(2*r)
+		t1 := 0.5 + e/(2*r)
+		v00.Texture = Vector{t0, t1, 0}
+		v01.Texture = Vector{t0, t0, 0}
+		v10.Texture = Vector{t1, t1, 0}
+		v11.Texture = Vector{t1, t0, 0}
+	}
+	buf = append(buf, rasterTriangle{v10, v00, v01, s10, s00, s01, 0, point})
+	buf = append(buf, rasterTriangle{v11, v10, v01, s11, s10, s01, 0, point})
+	return buf
+}
+
+// projectedRadius returns the radius in pixels of a sphere of radius r at
+// the position of v, which projects to s. The sphere's axes are offset and
+// run through the shader, and the largest singular value of the resulting
+// screen space offsets gives the radius.
+func (dc *Context) projectedRadius(v Vertex, s Vector, r float64) float64 {
+	var a, b, c float64
+	for _, axis := range []Vector{{r, 0, 0}, {0, r, 0}, {0, 0, r}} {
+		u := v
+		u.Position = v.Position.Add(axis)
+		u = dc.Shader.Vertex(u)
+		if u.Output.W <= 0 {
+			continue
+		}
+		d := dc.project(u).Sub(s)
+		a += d.X * d.X
+		b += d.X * d.Y
+		c += d.Y * d.Y
+	}
+	h := (a - c) / 2
+	return math.Sqrt((a+c)/2 + math.Sqrt(h*h+b*b))
+}
+
+func (dc *Context) DrawPoint(p *Point) RasterizeInfo {
+	dc.beginDraw()
+	return dc.rasterizeAll(dc.setupPoint(p, nil))
+}
+
+func (dc *Context) DrawPoints(points []*Point) RasterizeInfo {
+	info, _ := dc.DrawPointsContext(context.Background(), points)
+	return info
+}
+
+// DrawPointsContext is like DrawPoints but stops early if ctx is canceled,
+// returning ctx.Err().
+func (dc *Context) DrawPointsContext(ctx context.Context, points []*Point) (RasterizeInfo, error) {
+	return dc.drawBinned(ctx, len(points), nil, func(i int, buf []rasterTriangle) []rasterTriangle {
+		return dc.setupPoint(points[i], buf)
+	})
+}
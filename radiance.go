@@ -0,0 +1,127 @@
+package fauxgl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// LoadHDRTexture loads a Radiance RGBE (.hdr) image as a FloatTexture.
+func LoadHDRTexture(path string) (*FloatTexture, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return DecodeHDR(file)
+}
+
+// DecodeHDR decodes a Radiance RGBE image, flat or run length encoded, in
+// the standard -Y height +X width orientation.
+func DecodeHDR(r io.Reader) (*FloatTexture, error) {
+	br := bufio.NewReader(r)
+	// header, terminated by an empty line
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "#?") {
+		return nil, errors.New("invalid hdr signature")
+	}
+	for {
+		line, err = br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "FORMAT=") && line != "FORMAT=32-bit_rle_rgbe" {
+			return nil, fmt.Errorf("unsupported hdr format: %s", line[7:])
+		}
+	}
+	var width, height int
+	line, err = br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Sscanf(line, "-Y %d +X %d", &height, &width); err != nil {
+		return nil, fmt.Errorf("unsupported hdr resolution: %s", strings.TrimSpace(line))
+	}
+	pixels := make([]Color, width*height)
+	scanline := make([]byte, width*4)
+	for y := 0; y < height; y++ {
+		if err := readHDRScanline(br, scanline, width); err != nil {
+			return nil, err
+		}
+		for x := 0; x < width; x++ {
+			e := scanline[x*4+3]
+			if e == 0 {
+				pixels[y*width+x] = Black
+				continue
+			}
+			f := math.Ldexp(1, int(e)-(128+8))
+			r := float64(scanline[x*4+0]) * f
+			g := float64(scanline[x*4+1]) * f
+			b := float64(scanline[x*4+2]) * f
+			pixels[y*width+x] = Color{r, g, b, 1}
+		}
+	}
+	return NewFloatTexture(width, height, pixels), nil
+}
+
+// readHDRScanline reads one scanline of RGBE pixels into buf.
+func readHDRScanline(br *bufio.Reader, buf []byte, width int) error {
+	if _, err := io.ReadFull(br, buf[:4]); err != nil {
+		return err
+	}
+	if width < 8 || width > 0x7fff || buf[0] != 2 || buf[1] != 2 || buf[2]&0x80 != 0 {
+		// flat scanline
+		_, err := io.ReadFull(br, buf[4:])
+		return err
+	}
+	if int(buf[2])<<8|int(buf[3]) != width {
+		return errors.New("invalid hdr scanline width")
+	}
+	// run length encoded, one channel at a time
+	for c := 0; c < 4; c++ {
+		for x := 0; x < width; {
+			n, err := br.ReadByte()
+			if err != nil {
+				return err
+			}
+			if n > 128 {
+				n -= 128
+				v, err := br.ReadByte()
+				if err != nil {
+					return err
+				}
+				if x+int(n) > width {
+					return errors.New("invalid hdr run length")
+				}
+				for ; n > 0; n-- {
+					buf[x*4+c] = v
+					x++
+				}
+			} else {
+				if n == 0 || x+int(n) > width {
+					return errors.New("invalid hdr run length")
+				}
+				for ; n > 0; n-- {
+					v, err := br.ReadByte()
+					if err != nil {
+						return err
+					}
+					buf[x*4+c] = v
+					x++
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package fauxgl
+
+import (
+	"image"
+	"image/color"
+)
+
+// MultiShader is a Shader that writes additional named outputs per fragment.
+// FragmentOutputs is called instead of Fragment. It returns the color for the
+// ColorBuffer and fills outputs, which has one entry per name returned by
+// Outputs. Each output is stored in the Context render target of that name.
+type MultiShader interface {
+	Shader
+	Outputs() []string
+	FragmentOutputs(v Vertex, outputs []Color) Color
+}
+
+// RenderTarget is an additional per-pixel color buffer written by a
+// MultiShader.
+type RenderTarget struct {
+	Name   string
+	Width  int
+	Height int
+	Buffer []Color
+}
+
+func NewRenderTarget(name string, width, height int) *RenderTarget {
+	buffer := make([]Color, width*height)
+	return &RenderTarget{name, width, height, buffer}
+}
+
+func (t *RenderTarget) Clear(c Color) {
+	for i := range t.Buffer {
+		t.Buffer[i] = c
+	}
+}
+
+func (t *RenderTarget) At(x, y int) Color {
+	return t.Buffer[y*t.Width+x]
+}
+
+// Image returns the render target as a 16-bit image. Values are clamped to
+// the [0, 1] range.
+func (t *RenderTarget) Image() image.Image {
+	im := image.NewNRGBA64(image.Rect(0, 0, t.Width, t.Height))
+	const d = 0xffff
+	for i, c := range t.Buffer {
+		r := Clamp(c.R, 0, 1)
+		g := Clamp(c.G, 0, 1)
+		b := Clamp(c.B, 0, 1)
+		a := Clamp(c.A, 0, 1)
+		p := color.NRGBA64{uint16(r * d), uint16(g * d), uint16(b * d), uint16(a * d)}
+		im.SetNRGBA64(i%t.Width, i/t.Width, p)
+	}
+	return im
+}
+
+// RenderTarget returns the render target with the specified name, creating
+// it if needed.
+func (dc *Context) RenderTarget(name string) *RenderTarget {
+	for _, t := range dc.RenderTargets {
+		if t.Name == name {
+			return t
+		}
+	}
+	t := NewRenderTarget(name, dc.Width, dc.Height)
+	dc.RenderTargets = append(dc.RenderTargets, t)
+	return t
+}
+
+func (dc *Context) ClearRenderTargets() {
+	for _, t := range dc.RenderTargets {
+		t.Clear(Transparent)
+	}
+}
+
+// bindRenderTargets looks up the render targets for the outputs of the
+// current shader, if it is a MultiShader.
+func (dc *Context) bindRenderTargets() {
+	dc.outputs = dc.outputs[:0]
+	if s, ok := dc.Shader.(MultiShader); ok {
+		for _, name := range s.Outputs() {
+			dc.outputs = append(dc.outputs, dc.RenderTarget(name))
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package fauxgl
+
+// DrawCap fills in the cross section where a closed mesh is cut by the world
+// space plane through point with the specified normal. The plane, as
+// returned by WorldClipPlane with the same matrix, should be in ClipPlanes.
+// The cap is drawn with the current shader, using the stencil buffer to find
+// pixels where the plane is inside the mesh. The stencil buffer's low bit
+// must be clear beforehand and is cleared again afterward.
+func (dc *Context) DrawCap(mesh *Mesh, point, normal Vector, matrix Matrix) RasterizeInfo {
+	// save state
+	clipPlanesCopy := dc.ClipPlanes
+	writeColor, writeDepth, readDepth, cull := dc.WriteColor, dc.WriteDepth, dc.ReadDepth, dc.Cull
+	stencilTest, stencilFunc, stencilRef := dc.StencilTest, dc.StencilFunc, dc.StencilRef
+	stencilMask, stencilWriteMask := dc.StencilMask, dc.StencilWriteMask
+	stencilFail, stencilDepthFail, stencilPass := dc.StencilFail, dc.StencilDepthFail, dc.StencilPass
+
+	// count the clipped mesh's surfaces behind the plane; the plane is inside
+	// the mesh where the count is odd
+	dc.WriteColor = false
+	dc.WriteDepth = false
+	dc.ReadDepth = false
+	dc.Cull = CullNone
+	dc.StencilTest = true
+	dc.StencilFunc = CompareAlways
+	dc.StencilRef = 1
+	dc.StencilMask = 1
+	dc.StencilWriteMask = 1
+	dc.StencilFail = StencilKeep
+	dc.StencilDepthFail = StencilKeep
+	dc.StencilPass = StencilInvert
+	dc.DrawMesh(mesh)
+
+	// draw a quad on the plane where the count is odd, clearing the stencil
+	plane := WorldClipPlane(point, normal, matrix)
+	dc.ClipPlanes = nil
+	for _, p := range clipPlanesCopy {
+		if p != plane {
+			dc.ClipPlanes = append(dc.ClipPlanes, p)
+		}
+	}
+	dc.WriteColor = writeColor
+	dc.WriteDepth = writeDepth
+	dc.ReadDepth = readDepth
+	dc.StencilFunc = CompareEqual
+	dc.StencilDepthFail = StencilZero
+	dc.StencilPass = StencilZero
+	info := dc.DrawMesh(capQuad(mesh.BoundingBox(), point, normal))
+
+	// restore state
+	dc.ClipPlanes = clipPlanesCopy
+	dc.Cull = cull
+	dc.StencilTest, dc.StencilFunc, dc.StencilRef = stencilTest, stencilFunc, stencilRef
+	dc.StencilMask, dc.StencilWriteMask = stencilMask, stencilWriteMask
+	dc.StencilFail, dc.StencilDepthFail, dc.StencilPass = stencilFail, stencilDepthFail, stencilPass
+	return info
+}
+
+// capQuad returns a square on the plane that covers the box's cross section.
+// Its normals face the side of the plane that was clipped away.
+func capQuad(box Box, point, normal Vector) *Mesh {
+	n := normal.Normalize()
+	c := box.Anchor(Vector{0.5, 0.5, 0.5})
+	c = c.Sub(n.MulScalar(c.Sub(point).Dot(n)))
+	r := box.Size().Length()
+	u := n.Perpendicular().MulScalar(r)
+	v := n.Cross(u).Normalize().MulScalar(r)
+	p1 := c.Sub(u).Sub(v)
+	p2 := c.Add(u).Sub(v)
+	p3 := c.Add(u).Add(v)
+	p4 := c.Sub(u).Add(v)
+	m := n.Negate()
+	t1 := NewTriangle(Vertex{Position: p1, Normal: m}, Vertex{Position: p2, Normal: m}, Vertex{Position: p3, Normal: m})
+	t2 := NewTriangle(Vertex{Position: p1, Normal: m}, Vertex{Position: p3, Normal: m}, Vertex{Position: p4, Normal: m})
+	return NewTriangleMesh([]*Triangle{t1, t2})
+}
@@ -45,7 +45,20 @@ func (shader *TextureShader) Fragment(v Vertex) Color {
 	return shader.Texture.BilinearSample(v.Texture.X, v.Texture.Y)
 }
 
-// PhongShader implements Phong shading with an optional texture.
+func (shader *TextureShader) UsesDerivatives() bool {
+	return usesGradients(shader.Texture)
+}
+
+func (shader *TextureShader) FragmentDerivatives(v, dx, dy Vertex) Color {
+	if texture, ok := shader.Texture.(GradientTexture); ok {
+		return texture.GradientSample(v.Texture.X, v.Texture.Y, dx.Texture, dy.Texture)
+	}
+	return shader.Fragment(v)
+}
+
+// PhongShader implements Phong shading with an optional texture and
+// tangent space normal map. Normal maps require vertex tangents, see
+// Mesh.ComputeTangents.
 type PhongShader struct {
 	Matrix         Matrix
 	LightDirection Vector
@@ -55,7 +68,9 @@ type PhongShader struct {
 	DiffuseColor   Color
 	SpecularColor  Color
 	Texture        Texture
+	NormalMap      Texture
 	SpecularPower  float64
+	ShadowMap      *ShadowMap
 }
 
 func NewPhongShader(matrix Matrix, lightDirection, cameraPosition Vector) *PhongShader {
@@ -64,7 +79,7 @@ func NewPhongShader(matrix Matrix, lightDirection, cameraPosition Vector) *Phong
 	specular := Color{1, 1, 1, 1}
 	return &PhongShader{
 		matrix, lightDirection, cameraPosition,
-		Discard, ambient, diffuse, specular, nil, 32}
+		Discard, ambient, diffuse, specular, nil, nil, 32, nil}
 }
 
 func (shader *PhongShader) Vertex(v Vertex) Vertex {
@@ -81,16 +96,149 @@ func (shader *PhongShader) Fragment(v Vertex) Color {
 	if shader.Texture != nil {
 		color = shader.Texture.BilinearSample(v.Texture.X, v.Texture.Y)
 	}
-	diffuse := math.Max(v.Normal.Dot(shader.LightDirection), 0)
-	light = light.Add(shader.DiffuseColor.MulScalar(diffuse))
-	if diffuse > 0 && shader.SpecularPower > 0 {
+	normal := v.Normal
+	if shader.NormalMap != nil {
+		c := shader.NormalMap.BilinearSample(v.Texture.X, v.Texture.Y)
+		normal = TangentSpaceNormal(v, c)
+	}
+	diffuse := math.Max(normal.Dot(shader.LightDirection), 0)
+	visibility := 1.0
+	if diffuse > 0 && shader.ShadowMap != nil {
+		visibility = shader.ShadowMap.Visibility(v.Position)
+	}
+	light = light.Add(shader.DiffuseColor.MulScalar(diffuse * visibility))
+	if diffuse > 0 && visibility > 0 && shader.SpecularPower > 0 {
 		camera := shader.CameraPosition.Sub(v.Position).Normalize()
-		reflected := shader.LightDirection.Negate().Reflect(v.Normal)
+		reflected := shader.LightDirection.Negate().Reflect(normal)
 		specular := math.Max(camera.Dot(reflected), 0)
 		if specular > 0 {
-			specular = math.Pow(specular, shader.SpecularPower)
+			specular = math.Pow(specular, shader.SpecularPower) * visibility
 			light = light.Add(shader.SpecularColor.MulScalar(specular))
 		}
 	}
-	return color.Mul(light).Min(White).Alpha(color.A)
+	return color.Mul(light).Alpha(color.A)
+}
+
+// LitShader implements Phong or Blinn-Phong shading, summing the
+// contributions of any number of lights, with an optional texture and
+// tangent space normal map.
+type LitShader struct {
+	Matrix         Matrix
+	CameraPosition Vector
+	Lights         []Light
+	ObjectColor    Color
+	SpecularColor  Color
+	Texture        Texture
+	NormalMap      Texture
+	SpecularPower  float64
+	Blinn          bool
+}
+
+func NewLitShader(matrix Matrix, cameraPosition Vector, lights ...Light) *LitShader {
+	specular := Color{1, 1, 1, 1}
+	return &LitShader{
+		matrix, cameraPosition, lights,
+		Discard, specular, nil, nil, 32, true}
+}
+
+func (shader *LitShader) Vertex(v Vertex) Vertex {
+	v.Output = shader.Matrix.MulPositionW(v.Position)
+	return v
+}
+
+func (shader *LitShader) Fragment(v Vertex) Color {
+	color := v.Color
+	if shader.ObjectColor != Discard {
+		color = shader.ObjectColor
+	}
+	if shader.Texture != nil {
+		color = shader.Texture.BilinearSample(v.Texture.X, v.Texture.Y)
+	}
+	normal := v.Normal
+	if shader.NormalMap != nil {
+		c := shader.NormalMap.BilinearSample(v.Texture.X, v.Texture.Y)
+		normal = TangentSpaceNormal(v, c)
+	}
+	camera := shader.CameraPosition.Sub(v.Position).Normalize()
+	var diffuse, specular Color
+	for _, l := range shader.Lights {
+		direction, intensity := l.Illuminate(v.Position, normal)
+		if direction == (Vector{}) {
+			diffuse = diffuse.Add(intensity)
+			continue
+		}
+		d := normal.Dot(direction)
+		if d <= 0 || intensity == (Color{}) {
+			continue
+		}
+		diffuse = diffuse.Add(intensity.MulScalar(d))
+		if shader.SpecularPower <= 0 {
+			continue
+		}
+		var s float64
+		if shader.Blinn {
+			s = normal.Dot(direction.Add(camera).Normalize())
+		} else {
+			s = camera.Dot(direction.Negate().Reflect(normal))
+		}
+		if s > 0 {
+			s = math.Pow(s, shader.SpecularPower)
+			specular = specular.Add(intensity.MulScalar(s))
+		}
+	}
+	light := color.Mul(diffuse).Add(shader.SpecularColor.Mul(specular))
+	return light.Alpha(color.A)
+}
+
+// names of the render targets written by GBufferShader
+const (
+	GBufferAlbedo   = "albedo"
+	GBufferNormal   = "normal"
+	GBufferPosition = "position"
+	GBufferDepth    = "depth"
+)
+
+// GBufferShader writes surface attributes to render targets for deferred
+// shading and compositing. Normals are encoded in the [0, 1] range, positions
+// are unencoded and depth is the same value stored in the depth buffer.
+type GBufferShader struct {
+	Matrix      Matrix
+	ObjectColor Color
+	Texture     Texture
+}
+
+func NewGBufferShader(matrix Matrix) *GBufferShader {
+	return &GBufferShader{matrix, Discard, nil}
+}
+
+func (shader *GBufferShader) Vertex(v Vertex) Vertex {
+	v.Output = shader.Matrix.MulPositionW(v.Position)
+	return v
+}
+
+func (shader *GBufferShader) Fragment(v Vertex) Color {
+	color := v.Color
+	if shader.ObjectColor != Discard {
+		color = shader.ObjectColor
+	}
+	if shader.Texture != nil {
+		color = shader.Texture.BilinearSample(v.Texture.X, v.Texture.Y)
+	}
+	return color
+}
+
+func (shader *GBufferShader) Outputs() []string {
+	return []string{GBufferAlbedo, GBufferNormal, GBufferPosition, GBufferDepth}
+}
+
+func (shader *GBufferShader) FragmentOutputs(v Vertex, outputs []Color) Color {
+	color := shader.Fragment(v)
+	n := v.Normal.MulScalar(0.5).AddScalar(0.5)
+	p := v.Position
+	z := v.Output.Z/v.Output.W*0.5 + 0.5
+	outputs[0] = color
+	outputs[1] = Color{n.X, n.Y, n.Z, 1}
+	outputs[2] = Color{p.X, p.Y, p.Z, 1}
+	outputs[3] = Color{z, z, z, 1}
+	return color
 }
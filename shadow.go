@@ -0,0 +1,71 @@
+package fauxgl
+
+import "math"
+
+// ShadowMap stores the depth of a scene as seen from a light.
+type ShadowMap struct {
+	Matrix Matrix
+	Width  int
+	Height int
+	Depth  []float64
+	// Bias is subtracted from the depth of a point before it is compared to
+	// the shadow map, to avoid self-shadowing ("shadow acne").
+	Bias float64
+	// Radius is the radius, in texels, of the percentage-closer filtering
+	// kernel. Zero disables filtering.
+	Radius int
+	screen Matrix
+}
+
+// RenderShadowMap renders the depth of mesh through the light matrix, which
+// may use an orthographic or perspective projection.
+func RenderShadowMap(matrix Matrix, width, height int, mesh *Mesh) *ShadowMap {
+	dc := NewContext(width, height)
+	dc.WriteColor = false
+	dc.Cull = CullNone
+	dc.Shader = NewSolidColorShader(matrix, White)
+	dc.DrawMesh(mesh)
+	screen := Screen(width, height)
+	return &ShadowMap{matrix, width, height, dc.DepthBuffer, 5e-3, 1, screen}
+}
+
+// DirectionalShadowMatrix returns an orthographic light matrix that encloses
+// box, for a directional light pointing towards lightDirection (as in
+// PhongShader).
+func DirectionalShadowMatrix(lightDirection Vector, box Box) Matrix {
+	center := box.Anchor(Vector{0.5, 0.5, 0.5})
+	r := box.Size().Length() / 2
+	d := lightDirection.Normalize()
+	up := d.Perpendicular()
+	eye := center.Add(d.MulScalar(r * 2))
+	return LookAt(eye, center, up).Orthographic(-r, r, -r, r, r, r*3)
+}
+
+// Visibility returns the fraction of the light that reaches the position,
+// from 0 (fully shadowed) to 1 (fully lit).
+func (sm *ShadowMap) Visibility(position Vector) float64 {
+	p := sm.Matrix.MulPositionW(position)
+	if p.W <= 0 {
+		return 1
+	}
+	s := sm.screen.MulPosition(p.DivScalar(p.W).Vector())
+	x := int(math.Floor(s.X))
+	y := int(math.Floor(s.Y))
+	z := s.Z - sm.Bias
+	var lit, total int
+	for dy := -sm.Radius; dy <= sm.Radius; dy++ {
+		for dx := -sm.Radius; dx <= sm.Radius; dx++ {
+			total++
+			u := x + dx
+			v := y + dy
+			if u < 0 || v < 0 || u >= sm.Width || v >= sm.Height {
+				lit++
+				continue
+			}
+			if z <= sm.Depth[v*sm.Width+u] {
+				lit++
+			}
+		}
+	}
+	return float64(lit) / float64(total)
+}
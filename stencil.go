@@ -0,0 +1,60 @@
+package fauxgl
+
+type StencilOp int
+
+const (
+	_ StencilOp = iota
+	StencilKeep
+	StencilZero
+	StencilReplace
+	StencilIncr
+	StencilIncrWrap
+	StencilDecr
+	StencilDecrWrap
+	StencilInvert
+)
+
+func (dc *Context) ClearStencilBufferWith(value uint8) {
+	for i := range dc.StencilBuffer {
+		dc.StencilBuffer[i] = value
+	}
+}
+
+func (dc *Context) ClearStencilBuffer() {
+	dc.ClearStencilBufferWith(0)
+}
+
+func (dc *Context) stencilTest(j int) bool {
+	ref := float64(dc.StencilRef & dc.StencilMask)
+	value := float64(dc.StencilBuffer[j] & dc.StencilMask)
+	return dc.StencilFunc.compare(ref, value)
+}
+
+func (dc *Context) updateStencil(j int, op StencilOp) {
+	old := dc.StencilBuffer[j]
+	value := old
+	switch op {
+	case StencilZero:
+		value = 0
+	case StencilReplace:
+		value = dc.StencilRef
+	case StencilIncr:
+		if value < 0xff {
+			value++
+		}
+	case StencilIncrWrap:
+		value++
+	case StencilDecr:
+		if value > 0 {
+			value--
+		}
+	case StencilDecrWrap:
+		value--
+	case StencilInvert:
+		value = ^value
+	default:
+		return
+	}
+	mask := dc.StencilWriteMask
+	dc.StencilBuffer[j] = old&^mask | value&mask
+}
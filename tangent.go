@@ -0,0 +1,105 @@
+package fauxgl
+
+import "math"
+
+// ComputeTangents sets the tangent and bitangent of each vertex from the
+// mesh's normals and texture coordinates, for use with normal maps. Like
+// MikkTSpace, face tangents are projected onto the plane of each vertex
+// normal and averaged with angle weighting over the vertices that share a
+// position, normal, texture coordinate and handedness. The bitangent is the
+// cross product of the normal and tangent, negated where the texture is
+// mirrored, so tangent space normal maps baked against MikkTSpace render
+// without seams.
+func (m *Mesh) ComputeTangents() {
+	type key struct {
+		position, normal, texture Vector
+		mirrored                  bool
+	}
+	type corner struct {
+		v        *Vertex
+		mirrored bool
+	}
+	lookup := make(map[key]Vector)
+	corners := make([]corner, 0, len(m.Triangles)*3)
+	for _, t := range m.Triangles {
+		vs := [3]*Vertex{&t.V1, &t.V2, &t.V3}
+		e1 := t.V2.Position.Sub(t.V1.Position)
+		e2 := t.V3.Position.Sub(t.V1.Position)
+		d1 := t.V2.Texture.Sub(t.V1.Texture)
+		d2 := t.V3.Texture.Sub(t.V1.Texture)
+		r := d1.X*d2.Y - d2.X*d1.Y
+		mirrored := r < 0
+		var tangent Vector
+		if r != 0 {
+			tangent = e1.MulScalar(d2.Y).Sub(e2.MulScalar(d1.Y)).DivScalar(r)
+		}
+		for i, v := range vs {
+			corners = append(corners, corner{v, mirrored})
+			if tangent == (Vector{}) {
+				continue
+			}
+			n := v.Normal
+			s := projectNormalize(tangent, n)
+			a := projectNormalize(vs[(i+1)%3].Position.Sub(v.Position), n)
+			b := projectNormalize(vs[(i+2)%3].Position.Sub(v.Position), n)
+			angle := math.Acos(Clamp(a.Dot(b), -1, 1))
+			if math.IsNaN(s.X) || math.IsNaN(angle) {
+				continue
+			}
+			k := key{v.Position, n, v.Texture, mirrored}
+			lookup[k] = lookup[k].Add(s.MulScalar(angle))
+		}
+	}
+	for _, c := range corners {
+		v := c.v
+		n := v.Normal
+		k := key{v.Position, n, v.Texture, c.mirrored}
+		tangent := projectNormalize(lookup[k], n)
+		if math.IsNaN(tangent.X) {
+			tangent = perpendicular(n)
+		}
+		bitangent := n.Cross(tangent)
+		if c.mirrored {
+			bitangent = bitangent.Negate()
+		}
+		v.Tangent = tangent
+		v.Bitangent = bitangent
+	}
+}
+
+// TangentSpaceNormal returns the normal of v perturbed by c, a color sampled
+// from a tangent space normal map with components encoded in [0, 1]. The
+// vertex normal is returned if v has no tangents.
+func TangentSpaceNormal(v Vertex, c Color) Vector {
+	if v.Tangent == (Vector{}) {
+		return v.Normal
+	}
+	x := v.Tangent.MulScalar(c.R*2 - 1)
+	y := v.Bitangent.MulScalar(c.G*2 - 1)
+	z := v.Normal.MulScalar(c.B*2 - 1)
+	return x.Add(y).Add(z).Normalize()
+}
+
+// projectNormalize returns the unit vector of v projected onto the plane
+// with normal n. The result is NaN if the projection is zero.
+func projectNormalize(v, n Vector) Vector {
+	return v.Sub(n.MulScalar(n.Dot(v))).Normalize()
+}
+
+// perpendicular returns an arbitrary unit vector perpendicular to n.
+func perpendicular(n Vector) Vector {
+	a := Vector{1, 0, 0}
+	if math.Abs(n.X) > math.Abs(n.Y) {
+		a = Vector{0, 1, 0}
+	}
+	return projectNormalize(a, n)
+}
+
+// transformTangents transforms the tangent and bitangent of a vertex,
+// leaving them zero if they have not been computed.
+func transformTangents(matrix Matrix, tangent, bitangent Vector) (Vector, Vector) {
+	if tangent == (Vector{}) {
+		return tangent, bitangent
+	}
+	return matrix.MulDirection(tangent), matrix.MulDirection(bitangent)
+}
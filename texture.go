@@ -2,6 +2,7 @@ package fauxgl
 
 import (
 	"image"
+	"image/color"
 	"math"
 )
 
@@ -61,3 +62,176 @@ func (t *ImageTexture) BilinearSample(u, v float64) Color {
 	c = c.Add(c11.MulScalar(x * y))
 	return c
 }
+
+// FloatTexture is a texture of unclamped, linear colors, such as a high
+// dynamic range environment map. Pixels are stored in rows from the top.
+type FloatTexture struct {
+	Width  int
+	Height int
+	Pixels []Color
+}
+
+func NewFloatTexture(width, height int, pixels []Color) *FloatTexture {
+	return &FloatTexture{width, height, pixels}
+}
+
+func (t *FloatTexture) Sample(u, v float64) Color {
+	v = 1 - v
+	u -= math.Floor(u)
+	v -= math.Floor(v)
+	x := int(u * float64(t.Width))
+	y := int(v * float64(t.Height))
+	return t.Pixels[y*t.Width+x]
+}
+
+func (t *FloatTexture) BilinearSample(u, v float64) Color {
+	v = 1 - v
+	u -= math.Floor(u)
+	v -= math.Floor(v)
+	x := u * float64(t.Width-1)
+	y := v * float64(t.Height-1)
+	x0 := int(x)
+	y0 := int(y)
+	x1 := MinInt(x0+1, t.Width-1)
+	y1 := MinInt(y0+1, t.Height-1)
+	x -= float64(x0)
+	y -= float64(y0)
+	c00 := t.Pixels[y0*t.Width+x0]
+	c01 := t.Pixels[y1*t.Width+x0]
+	c10 := t.Pixels[y0*t.Width+x1]
+	c11 := t.Pixels[y1*t.Width+x1]
+	c := Color{}
+	c = c.Add(c00.MulScalar((1 - x) * (1 - y)))
+	c = c.Add(c10.MulScalar(x * (1 - y)))
+	c = c.Add(c01.MulScalar((1 - x) * y))
+	c = c.Add(c11.MulScalar(x * y))
+	return c
+}
+
+// Mipmap returns a MipmapTexture with levels down to 1x1 pixels, each made
+// by averaging 2x2 blocks of the previous level.
+func (t *FloatTexture) Mipmap() *MipmapTexture {
+	levels := []Texture{t}
+	for src := t; src.Width > 1 || src.Height > 1; {
+		w, h := MaxInt(src.Width/2, 1), MaxInt(src.Height/2, 1)
+		pixels := make([]Color, w*h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				x0, y0 := x*2, y*2
+				x1, y1 := MinInt(x0+1, src.Width-1), MinInt(y0+1, src.Height-1)
+				c := src.Pixels[y0*src.Width+x0]
+				c = c.Add(src.Pixels[y0*src.Width+x1])
+				c = c.Add(src.Pixels[y1*src.Width+x0])
+				c = c.Add(src.Pixels[y1*src.Width+x1])
+				pixels[y*w+x] = c.DivScalar(4)
+			}
+		}
+		src = NewFloatTexture(w, h, pixels)
+		levels = append(levels, src)
+	}
+	return &MipmapTexture{t.Width, t.Height, levels}
+}
+
+// GradientTexture is a Texture that can choose its level of detail from the
+// screen space derivatives of the texture coordinates.
+type GradientTexture interface {
+	Texture
+	GradientSample(u, v float64, dx, dy Vector) Color
+}
+
+// usesGradients reports whether any of the textures is a GradientTexture.
+func usesGradients(textures ...Texture) bool {
+	for _, t := range textures {
+		if _, ok := t.(GradientTexture); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleTexture samples t at the texture coordinates of v, using the
+// derivatives dx and dy when they are given and t supports them.
+func sampleTexture(t Texture, v Vertex, dx, dy *Vertex) Color {
+	if dx != nil {
+		if t, ok := t.(GradientTexture); ok {
+			return t.GradientSample(v.Texture.X, v.Texture.Y, dx.Texture, dy.Texture)
+		}
+	}
+	return t.BilinearSample(v.Texture.X, v.Texture.Y)
+}
+
+// MipmapTexture is a texture with a chain of successively halved levels,
+// sampled with trilinear filtering by GradientSample.
+type MipmapTexture struct {
+	Width  int
+	Height int
+	Levels []Texture
+}
+
+func LoadMipmapTexture(path string) (*MipmapTexture, error) {
+	im, err := LoadImage(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewMipmapTexture(im), nil
+}
+
+// NewMipmapTexture creates a texture with levels down to 1x1 pixels, each
+// made by averaging 2x2 blocks of the previous level.
+func NewMipmapTexture(im image.Image) *MipmapTexture {
+	size := im.Bounds().Size()
+	levels := []Texture{NewImageTexture(im)}
+	for w, h := size.X, size.Y; w > 1 || h > 1; {
+		src := im
+		w, h = MaxInt(w/2, 1), MaxInt(h/2, 1)
+		dst := image.NewRGBA64(image.Rect(0, 0, w, h))
+		sb := src.Bounds()
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				x0, y0 := sb.Min.X+x*2, sb.Min.Y+y*2
+				x1, y1 := MinInt(x0+1, sb.Max.X-1), MinInt(y0+1, sb.Max.Y-1)
+				c := MakeColor(src.At(x0, y0))
+				c = c.Add(MakeColor(src.At(x1, y0)))
+				c = c.Add(MakeColor(src.At(x0, y1)))
+				c = c.Add(MakeColor(src.At(x1, y1)))
+				// colors are premultiplied, as returned by MakeColor
+				c = c.DivScalar(4)
+				dst.SetRGBA64(x, y, color.RGBA64{
+					uint16(c.R * 0xffff), uint16(c.G * 0xffff),
+					uint16(c.B * 0xffff), uint16(c.A * 0xffff)})
+			}
+		}
+		im = dst
+		levels = append(levels, NewImageTexture(im))
+	}
+	return &MipmapTexture{size.X, size.Y, levels}
+}
+
+func (t *MipmapTexture) Sample(u, v float64) Color {
+	return t.Levels[0].Sample(u, v)
+}
+
+func (t *MipmapTexture) BilinearSample(u, v float64) Color {
+	return t.Levels[0].BilinearSample(u, v)
+}
+
+// LevelSample samples with bilinear filtering between texels and linear
+// filtering between the two levels nearest to lod.
+func (t *MipmapTexture) LevelSample(u, v, lod float64) Color {
+	lod = Clamp(lod, 0, float64(len(t.Levels)-1))
+	i := int(lod)
+	c := t.Levels[i].BilinearSample(u, v)
+	if f := lod - float64(i); f > 0 {
+		c = c.Lerp(t.Levels[i+1].BilinearSample(u, v), f)
+	}
+	return c
+}
+
+// GradientSample samples at the level of detail where one texel covers about
+// one pixel, given the derivatives of the texture coordinates.
+func (t *MipmapTexture) GradientSample(u, v float64, dx, dy Vector) Color {
+	w, h := float64(t.Width), float64(t.Height)
+	rx := math.Hypot(dx.X*w, dx.Y*h)
+	ry := math.Hypot(dy.X*w, dy.Y*h)
+	return t.LevelSample(u, v, math.Log2(math.Max(rx, ry)))
+}
@@ -0,0 +1,65 @@
+package fauxgl
+
+import "sort"
+
+// fragment is a translucent fragment stored for order-independent
+// transparency.
+type fragment struct {
+	Depth float64
+	Color Color
+	Mask  uint32
+}
+
+func (dc *Context) addFragment(i int, color Color, mask uint32, zs *[maxSamples]float64) {
+	var depth float64
+	for s := range dc.samples {
+		if mask&(1<<uint(s)) != 0 {
+			depth = zs[s]
+			break
+		}
+	}
+	dc.fragments[i] = append(dc.fragments[i], fragment{depth, color, mask})
+}
+
+// ResolveTransparency implements order-independent transparency with an
+// A-buffer. While Context.OrderIndependent is set, fragments with alpha < 1
+// are recorded per pixel instead of being blended. ResolveTransparency sorts
+// them from back to front and blends them into the color buffer, discarding
+// any that are hidden by opaque geometry. Image calls this automatically.
+func (dc *Context) ResolveTransparency() {
+	if dc.fragments == nil {
+		return
+	}
+	n := dc.Samples
+	reverse := dc.DepthFunc == CompareGreater || dc.DepthFunc == CompareGreaterEqual
+	for i, list := range dc.fragments {
+		if len(list) == 0 {
+			continue
+		}
+		sort.SliceStable(list, func(a, b int) bool {
+			if reverse {
+				return list[a].Depth < list[b].Depth
+			}
+			return list[a].Depth > list[b].Depth
+		})
+		x := i % dc.Width
+		y := i / dc.Width
+		for _, f := range list {
+			for s := 0; s < n; s++ {
+				if f.Mask&(1<<uint(s)) == 0 {
+					continue
+				}
+				j := i*n + s
+				if dc.ReadDepth && !dc.DepthFunc.compare(f.Depth, dc.DepthBuffer[j]) {
+					continue
+				}
+				if dc.SampleBuffer != nil {
+					dc.writeSample(j, f.Color)
+				} else {
+					dc.writePixel(x, y, f.Color)
+				}
+			}
+		}
+		dc.fragments[i] = list[:0]
+	}
+}
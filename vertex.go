@@ -1,14 +1,18 @@
 package fauxgl
 
 type Vertex struct {
-	Position Vector
-	Normal   Vector
-	Texture  Vector
-	Color    Color
-	Output   VectorW
-	// Vectors  []Vector
-	// Colors   []Color
-	// Floats   []float64
+	Position  Vector
+	Normal    Vector
+	Tangent   Vector
+	Bitangent Vector
+	Texture   Vector
+	Color     Color
+	Output    VectorW
+	// user defined attributes, interpolated like the fields above; the
+	// slices passed to Shader.Fragment are reused between fragments
+	Vectors []Vector
+	Colors  []Color
+	Floats  []float64
 }
 
 func (a Vertex) Outside() bool {
@@ -17,33 +21,53 @@ func (a Vertex) Outside() bool {
 
 func InterpolateVertexes(v1, v2, v3 Vertex, b VectorW) Vertex {
 	v := Vertex{}
+	interpolateVertexes(&v, v1, v2, v3, b)
+	return v
+}
+
+// interpolateVertexes is like InterpolateVertexes but stores the result in
+// v, reusing its attribute slices if they are large enough.
+func interpolateVertexes(v *Vertex, v1, v2, v3 Vertex, b VectorW) {
 	v.Position = InterpolateVectors(v1.Position, v2.Position, v3.Position, b)
 	v.Normal = InterpolateVectors(v1.Normal, v2.Normal, v3.Normal, b).Normalize()
+	v.Tangent = InterpolateVectors(v1.Tangent, v2.Tangent, v3.Tangent, b)
+	v.Bitangent = InterpolateVectors(v1.Bitangent, v2.Bitangent, v3.Bitangent, b)
 	v.Texture = InterpolateVectors(v1.Texture, v2.Texture, v3.Texture, b)
 	v.Color = InterpolateColors(v1.Color, v2.Color, v3.Color, b)
 	v.Output = InterpolateVectorWs(v1.Output, v2.Output, v3.Output, b)
-	// if v1.Vectors != nil {
-	// 	v.Vectors = make([]Vector, len(v1.Vectors))
-	// 	for i := range v.Vectors {
-	// 		v.Vectors[i] = InterpolateVectors(
-	// 			v1.Vectors[i], v2.Vectors[i], v3.Vectors[i], b)
-	// 	}
-	// }
-	// if v1.Colors != nil {
-	// 	v.Colors = make([]Color, len(v1.Colors))
-	// 	for i := range v.Colors {
-	// 		v.Colors[i] = InterpolateColors(
-	// 			v1.Colors[i], v2.Colors[i], v3.Colors[i], b)
-	// 	}
-	// }
-	// if v1.Floats != nil {
-	// 	v.Floats = make([]float64, len(v1.Floats))
-	// 	for i := range v.Floats {
-	// 		v.Floats[i] = InterpolateFloats(
-	// 			v1.Floats[i], v2.Floats[i], v3.Floats[i], b)
-	// 	}
-	// }
-	return v
+	v.Vectors = interpolateVectorSlices(v.Vectors[:0], v1.Vectors, v2.Vectors, v3.Vectors, b)
+	v.Colors = interpolateColorSlices(v.Colors[:0], v1.Colors, v2.Colors, v3.Colors, b)
+	v.Floats = interpolateFloatSlices(v.Floats[:0], v1.Floats, v2.Floats, v3.Floats, b)
+}
+
+func interpolateVectorSlices(dst, v1, v2, v3 []Vector, b VectorW) []Vector {
+	if v1 == nil {
+		return nil
+	}
+	for i := range v1 {
+		dst = append(dst, InterpolateVectors(v1[i], v2[i], v3[i], b))
+	}
+	return dst
+}
+
+func interpolateColorSlices(dst, v1, v2, v3 []Color, b VectorW) []Color {
+	if v1 == nil {
+		return nil
+	}
+	for i := range v1 {
+		dst = append(dst, InterpolateColors(v1[i], v2[i], v3[i], b))
+	}
+	return dst
+}
+
+func interpolateFloatSlices(dst, v1, v2, v3 []float64, b VectorW) []float64 {
+	if v1 == nil {
+		return nil
+	}
+	for i := range v1 {
+		dst = append(dst, InterpolateFloats(v1[i], v2[i], v3[i], b))
+	}
+	return dst
 }
 
 func InterpolateFloats(v1, v2, v3 float64, b VectorW) float64 {
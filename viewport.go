@@ -0,0 +1,60 @@
+package fauxgl
+
+import "image"
+
+// guardBand is how far, in pixels, primitives may extend past the viewport
+// before they are clipped in x and y. It keeps fixed point screen
+// coordinates small enough for exact edge functions.
+const guardBand = 1 << 16
+
+// SetViewport maps normalized device coordinates to the specified rectangle
+// of the color buffer, in pixels from the top left corner. Drawing is limited
+// to the viewport.
+func (dc *Context) SetViewport(x, y, w, h int) {
+	dc.viewport = image.Rect(x, y, x+w, y+h)
+	dc.screenMatrix = Screen(w, h).Translate(Vector{float64(x), float64(y), 0})
+}
+
+// Viewport returns the current viewport rectangle.
+func (dc *Context) Viewport() image.Rectangle {
+	return dc.viewport
+}
+
+// SetScissor limits drawing to the specified rectangle of the color buffer,
+// in pixels from the top left corner, and enables the scissor test.
+func (dc *Context) SetScissor(x, y, w, h int) {
+	dc.Scissor = image.Rect(x, y, x+w, y+h)
+	dc.ScissorTest = true
+}
+
+// updateBounds computes the region of the color buffer that may be drawn to
+// and the clip planes, including any user clip planes. Primitives are only
+// clipped in x and y when they extend past a guard band around the viewport;
+// otherwise the rasterizer limits them to the drawable region.
+func (dc *Context) updateBounds() {
+	b := dc.ColorBuffer.Bounds().Intersect(dc.viewport)
+	if dc.ScissorTest {
+		b = b.Intersect(dc.Scissor)
+	}
+	dc.bounds = b
+	// convert guard band to normalized device coordinates
+	g := dc.viewport.Inset(-guardBand)
+	inverse := dc.screenMatrix.Inverse()
+	p0 := inverse.MulPosition(Vector{float64(g.Min.X), float64(g.Max.Y), 0})
+	p1 := inverse.MulPosition(Vector{float64(g.Max.X), float64(g.Min.Y), 0})
+	dc.clipPlanes = clipPlanesForBounds(p0.X, p0.Y, p1.X, p1.Y)
+	// user clip planes keep points where plane.Dot(point) >= 0
+	for _, p := range dc.ClipPlanes {
+		dc.clipPlanes = append(dc.clipPlanes, clipPlane{VectorW{}, p})
+	}
+}
+
+// outside reports whether a vertex is outside the clip planes.
+func (dc *Context) outside(v Vertex) bool {
+	for _, p := range dc.clipPlanes {
+		if v.Output.Sub(p.P).Dot(p.N) < 0 {
+			return true
+		}
+	}
+	return false
+}